@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando/skipper/dataclients/kubernetes/xds"
+	"github.com/zalando/skipper/eskip"
+)
+
+// Client is the production entry point for the kubernetes dataclients: it
+// polls the cluster for RouteGroups and, when Options.GatewayAPIEnabled is
+// set, Gateway API resources, and converts both into a single list of eskip
+// routes.
+type Client struct {
+	cluster     *clusterClient
+	routeGroups *routeGroups
+	gatewayAPI  *gatewayAPI
+
+	quit chan struct{}
+}
+
+// New creates a Client for the given Options. If o.RouteGroupStatusEnabled
+// is set, it also starts the background status writer; call Close to stop
+// it.
+func New(o Options) (*Client, error) {
+	cc, err := newClusterClient(o)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		cluster:     cc,
+		routeGroups: newRouteGroups(o),
+		quit:        make(chan struct{}),
+	}
+
+	if o.GatewayAPIEnabled {
+		c.gatewayAPI = newGatewayAPI(o)
+	}
+
+	if o.RouteGroupStatusEnabled {
+		sw := newStatusWriter(cc, o.ControllerID, o.StatusWriteInterval, leaderFunc(o.LeaderElector))
+		c.routeGroups.status = sw
+		if c.gatewayAPI != nil {
+			c.gatewayAPI.status = sw
+		}
+
+		go sw.run(c.quit)
+	}
+
+	c.routeGroups.diagnostics = &diagnostics{}
+	if o.DiagnosticsListenAddress != "" {
+		go c.serveDiagnostics(o.DiagnosticsListenAddress)
+	}
+
+	if o.XDSListenAddress != "" {
+		go c.serveXDS(o.XDSListenAddress)
+	}
+
+	return c, nil
+}
+
+// serveDiagnostics mounts the RouteGroup transform diagnostics (see
+// TransformResult) on /kubernetes/diagnostics and blocks serving it until
+// the listener fails.
+func (c *Client) serveDiagnostics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/kubernetes/diagnostics", c.routeGroups.diagnostics)
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Errorf("Kubernetes diagnostics listener failed: %v.", err)
+	}
+}
+
+// serveXDS mounts a JSON snapshot of the xds package's rendering of the
+// current routes on /xds/routes and blocks serving it until the listener
+// fails. This is not an Envoy ADS gRPC endpoint: there is no
+// go-control-plane server behind it, just the RouteConfiguration/Cluster
+// values Envoy's RDS/CDS responses would be built from, for inspection.
+func (c *Client) serveXDS(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/xds/routes", c.xdsSnapshot)
+
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Errorf("Kubernetes xds listener failed: %v.", err)
+	}
+}
+
+func (c *Client) xdsSnapshot(w http.ResponseWriter, req *http.Request) {
+	routes, err := c.LoadAll()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	rc, clusters, err := xds.FromRoutes("kubernetes", routes)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		RouteConfiguration *xds.RouteConfiguration `json:"routeConfiguration"`
+		Clusters           []*xds.Cluster          `json:"clusters"`
+	}{rc, clusters})
+}
+
+func leaderFunc(le LeaderElector) func() bool {
+	if le == nil {
+		return nil
+	}
+
+	return le.IsLeader
+}
+
+// Close stops the background status writer started by New, if any.
+func (c *Client) Close() {
+	close(c.quit)
+}
+
+// LoadAll fetches the current cluster state and converts it into the full
+// set of eskip routes, combining RouteGroups with Gateway API resources
+// when the latter are enabled.
+func (c *Client) LoadAll() ([]*eskip.Route, error) {
+	state, err := c.cluster.fetchClusterState()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.convert(state)
+}
+
+func (c *Client) convert(state *clusterState) ([]*eskip.Route, error) {
+	var df defaultFilters
+
+	routes, _, err := c.routeGroups.convert(state, df)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.gatewayAPI != nil {
+		gr, err := c.gatewayAPI.convert(state, df)
+		if err != nil {
+			return nil, err
+		}
+
+		routes = append(routes, gr...)
+	}
+
+	return routes, nil
+}
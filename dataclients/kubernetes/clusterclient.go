@@ -0,0 +1,204 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// API paths polled by fetchClusterState. Gateway API paths are only polled
+// when Options.GatewayAPIEnabled is set.
+const (
+	routeGroupsListPath     = "/apis/zalando.org/v1/routegroups"
+	servicesListPath        = "/api/v1/services"
+	endpointsListPath       = "/api/v1/endpoints"
+	filterConfigsListPath   = "/apis/zalando.org/v1/filterconfigs"
+	gatewayClassesListPath  = "/apis/gateway.networking.k8s.io/v1/gatewayclasses"
+	gatewaysListPath        = "/apis/gateway.networking.k8s.io/v1/gateways"
+	httpRoutesListPath      = "/apis/gateway.networking.k8s.io/v1/httproutes"
+	tlsRoutesListPath       = "/apis/gateway.networking.k8s.io/v1alpha2/tlsroutes"
+	tcpRoutesListPath       = "/apis/gateway.networking.k8s.io/v1alpha2/tcproutes"
+	referenceGrantsListPath = "/apis/gateway.networking.k8s.io/v1beta1/referencegrants"
+)
+
+// clusterClient fetches the resources that make up a clusterState snapshot
+// from the Kubernetes API server, and PATCHes status subresources back onto
+// them. It's deliberately a thin HTTP client rather than a full client-go
+// informer setup, matching the rest of this dataclient's poll-and-diff
+// design: fetchClusterState is called on every Client.LoadAll call.
+type clusterClient struct {
+	httpClient        *http.Client
+	apiURL            string
+	token             string
+	gatewayAPIEnabled bool
+}
+
+func newClusterClient(o Options) (*clusterClient, error) {
+	c := &clusterClient{
+		httpClient:        http.DefaultClient,
+		apiURL:            strings.TrimRight(o.KubernetesURL, "/"),
+		gatewayAPIEnabled: o.GatewayAPIEnabled,
+	}
+
+	if o.KubernetesTokenFile != "" {
+		token, err := os.ReadFile(o.KubernetesTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read kubernetes token file: %w", err)
+		}
+
+		c.token = strings.TrimSpace(string(token))
+	}
+
+	return c, nil
+}
+
+func (c *clusterClient) do(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, c.apiURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/merge-patch+json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status code %d from %s: %s", resp.StatusCode, path, string(b))
+	}
+
+	return resp, nil
+}
+
+func (c *clusterClient) get(path string, into interface{}) error {
+	resp, err := c.do(http.MethodGet, path, nil)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(into)
+}
+
+// patchStatus sends a merge-patch status update, as produced by
+// statusWriter, to the given status subresource path.
+func (c *clusterClient) patchStatus(path string, body []byte) error {
+	resp, err := c.do(http.MethodPatch, path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	return resp.Body.Close()
+}
+
+// fetchClusterState polls the API server for every resource kind the
+// dataclients need and assembles them into a clusterState snapshot.
+func (c *clusterClient) fetchClusterState() (*clusterState, error) {
+	var routeGroups routeGroupList
+	if err := c.get(routeGroupsListPath, &routeGroups); err != nil {
+		return nil, fmt.Errorf("failed to fetch routegroups: %w", err)
+	}
+
+	var services serviceList
+	if err := c.get(servicesListPath, &services); err != nil {
+		return nil, fmt.Errorf("failed to fetch services: %w", err)
+	}
+
+	var endpoints endpointList
+	if err := c.get(endpointsListPath, &endpoints); err != nil {
+		return nil, fmt.Errorf("failed to fetch endpoints: %w", err)
+	}
+
+	var filterConfigs filterConfigList
+	if err := c.get(filterConfigsListPath, &filterConfigs); err != nil {
+		return nil, fmt.Errorf("failed to fetch filterconfigs: %w", err)
+	}
+
+	state := &clusterState{
+		routeGroups:   routeGroups.Items,
+		services:      make(map[string]*service),
+		endpoints:     make(map[string]*endpointItem),
+		filterConfigs: make(map[string]*filterConfig),
+	}
+
+	for _, s := range services.Items {
+		if s.Meta == nil {
+			continue
+		}
+
+		state.services[resourceKey(namespaceString(s.Meta.Namespace), s.Meta.Name)] = s
+	}
+
+	for _, e := range endpoints.Items {
+		if e.Meta == nil {
+			continue
+		}
+
+		state.endpoints[resourceKey(namespaceString(e.Meta.Namespace), e.Meta.Name)] = e
+	}
+
+	for _, fc := range filterConfigs.Items {
+		if fc.Metadata == nil {
+			continue
+		}
+
+		state.filterConfigs[resourceKey(namespaceString(fc.Metadata.Namespace), fc.Metadata.Name)] = fc
+	}
+
+	if !c.gatewayAPIEnabled {
+		return state, nil
+	}
+
+	var gatewayClasses gatewayClassList
+	if err := c.get(gatewayClassesListPath, &gatewayClasses); err != nil {
+		return nil, fmt.Errorf("failed to fetch gatewayclasses: %w", err)
+	}
+
+	var gateways gatewayList
+	if err := c.get(gatewaysListPath, &gateways); err != nil {
+		return nil, fmt.Errorf("failed to fetch gateways: %w", err)
+	}
+
+	var httpRoutes httpRouteList
+	if err := c.get(httpRoutesListPath, &httpRoutes); err != nil {
+		return nil, fmt.Errorf("failed to fetch httproutes: %w", err)
+	}
+
+	var tlsRoutes tlsRouteList
+	if err := c.get(tlsRoutesListPath, &tlsRoutes); err != nil {
+		return nil, fmt.Errorf("failed to fetch tlsroutes: %w", err)
+	}
+
+	var tcpRoutes tcpRouteList
+	if err := c.get(tcpRoutesListPath, &tcpRoutes); err != nil {
+		return nil, fmt.Errorf("failed to fetch tcproutes: %w", err)
+	}
+
+	var referenceGrants referenceGrantList
+	if err := c.get(referenceGrantsListPath, &referenceGrants); err != nil {
+		return nil, fmt.Errorf("failed to fetch referencegrants: %w", err)
+	}
+
+	state.gatewayClasses = gatewayClasses.Items
+	state.gateways = gateways.Items
+	state.httpRoutes = httpRoutes.Items
+	state.tlsRoutes = tlsRoutes.Items
+	state.tcpRoutes = tcpRoutes.Items
+	state.referenceGrants = referenceGrants.Items
+
+	return state, nil
+}
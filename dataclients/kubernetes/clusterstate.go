@@ -0,0 +1,112 @@
+package kubernetes
+
+import "fmt"
+
+// clusterState is a point-in-time snapshot of every Kubernetes resource the
+// dataclients need in order to compute eskip routes: RouteGroups, the
+// Gateway API resources (when enabled) -- including TLSRoutes/TCPRoutes,
+// which are fetched and stored but not yet converted, since Skipper does
+// not terminate TLS/TCP itself -- the Services and Endpoints they
+// reference, and the FilterConfigs referenced via Gateway API ExtensionRef
+// filters. It's rebuilt from scratch on every poll by
+// clusterClient.fetchClusterState and handed to routeGroups.convert and
+// gatewayAPI.convert, which only ever read from it.
+type clusterState struct {
+	routeGroups     []*routeGroupItem
+	gatewayClasses  []*gatewayClass
+	gateways        []*gatewayItem
+	httpRoutes      []*httpRouteItem
+	tlsRoutes       []*tlsRouteItem
+	tcpRoutes       []*tcpRouteItem
+	referenceGrants []*referenceGrant
+
+	services      map[string]*service
+	endpoints     map[string]*endpointItem
+	filterConfigs map[string]*filterConfig
+}
+
+type routeGroupList struct {
+	Items []*routeGroupItem `json:"items"`
+}
+
+type serviceList struct {
+	Items []*service `json:"items"`
+}
+
+type endpointList struct {
+	Items []*endpointItem `json:"items"`
+}
+
+// endpointItem is the minimal shape of a Kubernetes Endpoints object that
+// getEndpointsByTarget needs: the ready addresses and the ports they're
+// listening on, per subset.
+type endpointItem struct {
+	Meta    *metadata         `json:"metadata"`
+	Subsets []*endpointSubset `json:"subsets"`
+}
+
+type endpointSubset struct {
+	Addresses []*endpointAddress `json:"addresses"`
+	Ports     []*endpointPort    `json:"ports"`
+}
+
+type endpointAddress struct {
+	IP string `json:"ip"`
+}
+
+type endpointPort struct {
+	Name string `json:"name"`
+	Port int    `json:"port"`
+}
+
+type filterConfigList struct {
+	Items []*filterConfig `json:"items"`
+}
+
+func resourceKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+func (s *clusterState) getService(namespace, name string) (*service, error) {
+	svc, ok := s.services[resourceKey(namespace, name)]
+	if !ok {
+		return nil, fmt.Errorf("service not found: %s/%s", namespace, name)
+	}
+
+	return svc, nil
+}
+
+// getEndpointsByTarget returns the "http://ip:port" endpoint addresses of
+// the named service's subsets that serve targetPort, or nil if the service
+// has no ready endpoints on that port.
+func (s *clusterState) getEndpointsByTarget(namespace, name string, targetPort int) []string {
+	ep, ok := s.endpoints[resourceKey(namespace, name)]
+	if !ok {
+		return nil
+	}
+
+	var eps []string
+	for _, subset := range ep.Subsets {
+		servesTarget := false
+		for _, p := range subset.Ports {
+			if p.Port == targetPort {
+				servesTarget = true
+				break
+			}
+		}
+
+		if !servesTarget {
+			continue
+		}
+
+		for _, a := range subset.Addresses {
+			eps = append(eps, fmt.Sprintf("http://%s:%d", a.IP, targetPort))
+		}
+	}
+
+	return eps
+}
+
+func (s *clusterState) getFilterConfig(namespace, name string) *filterConfig {
+	return s.filterConfigs[resourceKey(namespace, name)]
+}
@@ -0,0 +1,135 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// dropReason is a typed classification of why a route was dropped during
+// RouteGroup transformation, replacing the previous pattern of a single
+// log.Errorf call per failure.
+type dropReason string
+
+const (
+	reasonInvalidBackendRef      dropReason = "InvalidBackendRef"
+	reasonUnsupportedServiceType dropReason = "UnsupportedServiceType"
+	reasonServicePortNotFound    dropReason = "ServicePortNotFound"
+	reasonEndpointsMissing       dropReason = "EndpointsMissing"
+	reasonDefaultFiltersFailed   dropReason = "DefaultFiltersFailed"
+	reasonPredicateParseError    dropReason = "PredicateParseError"
+	reasonFilterParseError       dropReason = "FilterParseError"
+	reasonMissingBackends        dropReason = "MissingBackends"
+)
+
+// transformError is returned by the conversion helpers instead of a bare
+// error, so that callers can classify and report the failure without
+// string-matching the message.
+type transformError struct {
+	reason  dropReason
+	message string
+}
+
+func (e *transformError) Error() string { return e.message }
+
+func newTransformError(reason dropReason, format string, args ...interface{}) *transformError {
+	return &transformError{reason: reason, message: fmt.Sprintf(format, args...)}
+}
+
+func reasonOf(err error) dropReason {
+	if te, ok := err.(*transformError); ok {
+		return te.reason
+	}
+
+	return reasonInvalidBackendRef
+}
+
+// objectRef identifies the Kubernetes object a diagnostic entry is about.
+type objectRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// droppedRoute records a single route that didn't make it into the eskip
+// output, and why.
+type droppedRoute struct {
+	RouteGroup objectRef  `json:"routeGroup"`
+	RouteID    string     `json:"routeId,omitempty"`
+	Reason     dropReason `json:"reason"`
+	Message    string     `json:"message"`
+}
+
+// TransformResult is the outcome of converting the RouteGroups found in one
+// clusterState snapshot: which routes were accepted, and which were
+// dropped and why. It lets operators debug a bad conversion at scale,
+// instead of scraping logs.
+type TransformResult struct {
+	Accepted []string        `json:"accepted"`
+	Dropped  []*droppedRoute `json:"dropped"`
+}
+
+func (t *TransformResult) addDropped(rg *routeGroupItem, routeID string, err error) {
+	t.Dropped = append(t.Dropped, &droppedRoute{
+		RouteGroup: objectRef{
+			Namespace: namespaceString(rg.Metadata.Namespace),
+			Name:      rg.Metadata.Name,
+		},
+		RouteID: routeID,
+		Reason:  reasonOf(err),
+		Message: err.Error(),
+	})
+}
+
+var dropCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "skipper",
+		Subsystem: "kubernetes",
+		Name:      "routegroup_dropped_routes_total",
+		Help:      "Number of RouteGroup routes dropped during conversion, by reason and namespace.",
+	},
+	[]string{"reason", "namespace"},
+)
+
+func init() {
+	prometheus.MustRegister(dropCounter)
+}
+
+func recordDropped(result *TransformResult) {
+	for _, d := range result.Dropped {
+		dropCounter.WithLabelValues(string(d.Reason), d.RouteGroup.Namespace).Inc()
+	}
+}
+
+// diagnostics is an HTTP handler exposing the last TransformResult for
+// /kubernetes/diagnostics, so operators can inspect why specific routes
+// were dropped without grepping logs.
+type diagnostics struct {
+	mu     sync.Mutex
+	result *TransformResult
+}
+
+func (d *diagnostics) set(result *TransformResult) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.result = result
+
+	recordDropped(result)
+}
+
+func (d *diagnostics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	result := d.result
+	d.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	if result == nil {
+		result = &TransformResult{}
+	}
+
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,387 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/zalando/skipper/eskip"
+)
+
+// skipperGatewayControllerName is the controllerName value that a
+// GatewayClass must declare in order for Skipper to reconcile Gateways
+// that reference it.
+const skipperGatewayControllerName = "zalando.org/skipper"
+
+// gatewayAPI converts Gateway API resources (GatewayClass, Gateway,
+// HTTPRoute, ReferenceGrant) into eskip routes; TLSRoutes and TCPRoutes in
+// the cluster state are not converted. It is
+// the Gateway API analog of routeGroups, and follows the same conversion
+// shape: a convert() method that takes the current clusterState and the
+// default filter configuration, and returns a flat list of eskip.Route.
+type gatewayAPI struct {
+	options Options
+	status  *statusWriter
+}
+
+// gatewayContext carries the state needed while converting a single
+// Gateway's listeners and attached HTTPRoutes into eskip routes.
+type gatewayContext struct {
+	clusterState    *clusterState
+	defaultFilters  defaultFilters
+	gateway         *gatewayItem
+	controllerNames map[string]bool
+}
+
+func newGatewayAPI(o Options) *gatewayAPI {
+	return &gatewayAPI{options: o}
+}
+
+// reportGatewayStatus and reportHTTPRouteStatus queue a status update for a
+// Gateway/HTTPRoute, if a statusWriter is configured, reusing the same
+// statusWriter instance and resourceStatus shape routeGroups.reportStatus
+// writes through (see statusWriter's doc comment). This is a simplified
+// Accepted/Reason patch, not the full upstream
+// status.listeners[]/status.parents[].conditions[] shape the Gateway API
+// spec describes; Skipper doesn't reconcile per-listener or per-parent
+// conditions yet.
+func (g *gatewayAPI) reportGatewayStatus(gw *gatewayItem, status *resourceStatus) {
+	if g.status == nil {
+		return
+	}
+
+	status.ObservedGeneration = gw.Metadata.Generation
+	path := fmt.Sprintf(
+		"/apis/gateway.networking.k8s.io/v1/namespaces/%s/gateways/%s/status",
+		namespaceString(gw.Metadata.Namespace),
+		gw.Metadata.Name,
+	)
+
+	g.status.update("gateway", namespaceString(gw.Metadata.Namespace), gw.Metadata.Name, path, status)
+}
+
+func (g *gatewayAPI) reportHTTPRouteStatus(hr *httpRouteItem, status *resourceStatus) {
+	if g.status == nil {
+		return
+	}
+
+	status.ObservedGeneration = hr.Metadata.Generation
+	path := fmt.Sprintf(
+		"/apis/gateway.networking.k8s.io/v1/namespaces/%s/httproutes/%s/status",
+		namespaceString(hr.Metadata.Namespace),
+		hr.Metadata.Name,
+	)
+
+	g.status.update("httproute", namespaceString(hr.Metadata.Namespace), hr.Metadata.Name, path, status)
+}
+
+// matchingGatewayClasses returns the names of the GatewayClasses whose
+// controllerName matches skipperGatewayControllerName; only Gateways
+// referencing one of these classes are processed.
+func matchingGatewayClasses(classes []*gatewayClass) map[string]bool {
+	m := make(map[string]bool)
+	for _, c := range classes {
+		if c.Metadata == nil || c.Spec == nil {
+			continue
+		}
+
+		if c.Spec.ControllerName == skipperGatewayControllerName {
+			m[c.Metadata.Name] = true
+		}
+	}
+
+	return m
+}
+
+// referenceAllowed reports whether a cross-namespace backend reference
+// from fromNamespace to a Service named toName in toNamespace is covered
+// by a ReferenceGrant in toNamespace.
+func referenceAllowed(grants []*referenceGrant, fromNamespace, toNamespace, toName string) bool {
+	if fromNamespace == toNamespace {
+		return true
+	}
+
+	for _, g := range grants {
+		if g.Metadata == nil || g.Spec == nil || g.Metadata.Namespace != toNamespace {
+			continue
+		}
+
+		var fromOK bool
+		for _, f := range g.Spec.From {
+			if f.Kind == "HTTPRoute" && f.Namespace == fromNamespace {
+				fromOK = true
+				break
+			}
+		}
+
+		if !fromOK {
+			continue
+		}
+
+		for _, t := range g.Spec.To {
+			if t.Kind == "Service" && (t.Name == "" || t.Name == toName) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func httpRouteAttachesToGateway(hr *httpRouteItem, gw *gatewayItem) bool {
+	for _, p := range hr.Spec.ParentRefs {
+		ns := p.Namespace
+		if ns == "" {
+			ns = namespaceString(hr.Metadata.Namespace)
+		}
+
+		if ns == namespaceString(gw.Metadata.Namespace) && p.Name == gw.Metadata.Name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// convertHTTPRoute builds the eskip routes for a single HTTPRoute attached
+// to one listener of a Gateway.
+func convertHTTPRoute(ctx *gatewayContext, listener *gatewayListener, hr *httpRouteItem) ([]*eskip.Route, error) {
+	hostRx := createHostRx(listenerHosts(listener, hr.Spec.Hostnames)...)
+
+	var routes []*eskip.Route
+	for ruleIndex, rule := range hr.Spec.Rules {
+		// Traffic must be calculated only over the backendRefs that will
+		// actually produce a route: a rejected cross-namespace reference
+		// (see referenceAllowed below) never gets a route, so including it
+		// in calculateTraffic would leave its share of requests with
+		// nothing to match, instead of redistributing it among the
+		// backendRefs that do route.
+		var allowedRefs []*httpBackendRef
+		for _, bref := range rule.BackendRefs {
+			if !referenceAllowed(ctx.clusterState.referenceGrants, namespaceString(hr.Metadata.Namespace), backendNamespace(hr, bref), bref.Name) {
+				log.Errorf(
+					"Rejecting cross-namespace backend reference in httproute/%s/%s: %s",
+					namespaceString(hr.Metadata.Namespace), hr.Metadata.Name, bref.Name,
+				)
+				continue
+			}
+
+			allowedRefs = append(allowedRefs, bref)
+		}
+
+		traffic := calculateTraffic(toBackendReferences(allowedRefs))
+
+		for matchIndex, m := range matchesOrDefault(rule.Matches) {
+			matchID := rgRouteID(
+				toSymbol(namespaceString(hr.Metadata.Namespace)),
+				toSymbol(hr.Metadata.Name),
+				toSymbol(listener.Name),
+				ruleIndex*1000+matchIndex,
+				0,
+			)
+
+			teeFilters, shuntRoutes, err := mirrorFilters(ctx, namespaceString(hr.Metadata.Namespace), rule.Filters, matchID)
+			if err != nil {
+				log.Errorf("Failed to apply mirror filters for httproute/%s/%s: %v", namespaceString(hr.Metadata.Namespace), hr.Metadata.Name, err)
+				continue
+			}
+
+			routes = append(routes, shuntRoutes...)
+
+			for backendIndex, bref := range allowedRefs {
+				r := &eskip.Route{
+					Id: rgRouteID(
+						toSymbol(namespaceString(hr.Metadata.Namespace)),
+						toSymbol(hr.Metadata.Name),
+						toSymbol(listener.Name),
+						ruleIndex*1000+matchIndex,
+						backendIndex,
+					),
+				}
+
+				if hostRx != "" {
+					r.Predicates = appendPredicate(r.Predicates, "Host", hostRx)
+				}
+
+				applyHTTPRouteMatch(r, m)
+
+				if err := applyFilters(ctx, namespaceString(hr.Metadata.Namespace), rule.Filters, r); err != nil {
+					log.Errorf("Failed to apply filters for httproute/%s/%s: %v", namespaceString(hr.Metadata.Namespace), hr.Metadata.Name, err)
+					continue
+				}
+
+				r.Filters = append(append([]*eskip.Filter{}, teeFilters...), r.Filters...)
+
+				if r.Shunt {
+					routes = append(routes, r)
+					continue
+				}
+
+				if err := applyHTTPBackendRef(ctx, hr, bref, r); err != nil {
+					log.Errorf("Failed to resolve backend for httproute/%s/%s: %v", namespaceString(hr.Metadata.Namespace), hr.Metadata.Name, err)
+					continue
+				}
+
+				if t := traffic[bref.Name]; t < 1 {
+					r.Predicates = appendPredicate(r.Predicates, "Traffic", t)
+				}
+
+				routes = append(routes, r)
+			}
+		}
+	}
+
+	return routes, nil
+}
+
+func matchesOrDefault(m []*httpRouteMatch) []*httpRouteMatch {
+	if len(m) == 0 {
+		return []*httpRouteMatch{{}}
+	}
+
+	return m
+}
+
+func listenerHosts(listener *gatewayListener, routeHostnames []string) []string {
+	if len(routeHostnames) > 0 {
+		return routeHostnames
+	}
+
+	if listener.Hostname != "" {
+		return []string{listener.Hostname}
+	}
+
+	return nil
+}
+
+func backendNamespace(hr *httpRouteItem, bref *httpBackendRef) string {
+	if bref.Namespace != "" {
+		return bref.Namespace
+	}
+
+	return namespaceString(hr.Metadata.Namespace)
+}
+
+func toBackendReferences(refs []*httpBackendRef) []*backendReference {
+	brefs := make([]*backendReference, len(refs))
+	for i, r := range refs {
+		brefs[i] = &backendReference{BackendName: r.Name, Weight: r.Weight}
+	}
+
+	return brefs
+}
+
+func applyHTTPRouteMatch(r *eskip.Route, m *httpRouteMatch) {
+	if m.Path != nil {
+		switch m.Path.Type {
+		case "Exact", "":
+			if m.Path.Value != "" {
+				r.Predicates = appendPredicate(r.Predicates, "Path", m.Path.Value)
+			}
+		case "PathPrefix":
+			r.Predicates = appendPredicate(r.Predicates, "PathSubtree", m.Path.Value)
+		case "RegularExpression":
+			r.Predicates = appendPredicate(r.Predicates, "PathRegexp", m.Path.Value)
+		}
+	}
+
+	if m.Method != "" {
+		r.Predicates = appendPredicate(r.Predicates, "Method", m.Method)
+	}
+
+	for _, h := range m.Headers {
+		if h.Type == "RegularExpression" {
+			r.Predicates = appendPredicate(r.Predicates, "HeaderRegexp", h.Name, h.Value)
+		} else {
+			r.Predicates = appendPredicate(r.Predicates, "Header", h.Name, h.Value)
+		}
+	}
+
+	for _, q := range m.QueryParams {
+		r.Predicates = appendPredicate(r.Predicates, "QueryParam", q.Name, q.Value)
+	}
+}
+
+// applyHTTPBackendRef resolves a Gateway API backendRef the same way a
+// RouteGroup service backend is resolved: only ClusterIP services are
+// supported, via applyServiceBackend. Like applyBackend's serviceBackend
+// case, it also applies the cluster-wide default filters configured for
+// the backend's service; a failure to look them up is logged and otherwise
+// ignored, the route isn't dropped over it.
+func applyHTTPBackendRef(ctx *gatewayContext, hr *httpRouteItem, bref *httpBackendRef, r *eskip.Route) error {
+	be := &skipperBackend{
+		Type:        serviceBackend,
+		ServiceName: bref.Name,
+		ServicePort: bref.Port,
+	}
+
+	rgCtx := &routeGroupContext{
+		clusterState:   ctx.clusterState,
+		defaultFilters: ctx.defaultFilters,
+		routeGroup: &routeGroupItem{
+			Metadata: &metadata{Namespace: backendNamespace(hr, bref)},
+		},
+	}
+
+	r.BackendType = serviceBackend
+	if err := applyServiceBackend(rgCtx, be, r); err != nil {
+		return err
+	}
+
+	if err := applyDefaultFilters(rgCtx, bref.Name, r); err != nil {
+		log.Errorf("Failed to retrieve default filters: %v.", err)
+	}
+
+	return nil
+}
+
+// convert computes the eskip routes for all Gateways whose GatewayClass
+// controllerName matches skipperGatewayControllerName, and the HTTPRoutes
+// attached to them. It has the same signature shape as routeGroups.convert
+// so that both dataclients can be combined by the caller.
+func (g *gatewayAPI) convert(s *clusterState, df defaultFilters) ([]*eskip.Route, error) {
+	classes := matchingGatewayClasses(s.gatewayClasses)
+
+	var rs []*eskip.Route
+	for _, gw := range s.gateways {
+		if gw.Metadata == nil || gw.Spec == nil {
+			continue
+		}
+
+		if !classes[gw.Spec.GatewayClassName] {
+			continue
+		}
+
+		ctx := &gatewayContext{
+			clusterState:   s,
+			defaultFilters: df,
+			gateway:        gw,
+		}
+
+		g.reportGatewayStatus(gw, &resourceStatus{Accepted: true, Reason: statusReasonAccepted})
+
+		for _, listener := range gw.Spec.Listeners {
+			for _, hr := range s.httpRoutes {
+				if hr.Metadata == nil || hr.Spec == nil || !httpRouteAttachesToGateway(hr, gw) {
+					continue
+				}
+
+				ri, err := convertHTTPRoute(ctx, listener, hr)
+				if err != nil {
+					log.Errorf("Error transforming httproute %s: %v.", hr.Metadata.Name, err)
+					g.reportHTTPRouteStatus(hr, &resourceStatus{Reason: statusReasonConversionError, Message: err.Error()})
+					continue
+				}
+
+				g.reportHTTPRouteStatus(hr, &resourceStatus{
+					Accepted: true,
+					Reason:   statusReasonAccepted,
+					RouteIDs: routeIDs(ri),
+				})
+
+				rs = append(rs, ri...)
+			}
+		}
+	}
+
+	return rs, nil
+}
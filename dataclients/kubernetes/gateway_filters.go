@@ -0,0 +1,298 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// Gateway API HTTPRouteFilter.Type values this converter understands.
+const (
+	filterRequestHeaderModifier  = "RequestHeaderModifier"
+	filterResponseHeaderModifier = "ResponseHeaderModifier"
+	filterRequestRedirect        = "RequestRedirect"
+	filterURLRewrite             = "URLRewrite"
+	filterRequestMirror          = "RequestMirror"
+	filterExtensionRef           = "ExtensionRef"
+)
+
+type httpRouteFilter struct {
+	Type                   string                     `json:"type"`
+	RequestHeaderModifier  *httpHeaderFilter          `json:"requestHeaderModifier"`
+	ResponseHeaderModifier *httpHeaderFilter          `json:"responseHeaderModifier"`
+	RequestRedirect        *httpRequestRedirectFilter `json:"requestRedirect"`
+	URLRewrite             *httpURLRewriteFilter      `json:"urlRewrite"`
+	RequestMirror          *httpRequestMirrorFilter   `json:"requestMirror"`
+	ExtensionRef           *localObjectReference      `json:"extensionRef"`
+}
+
+type httpHeaderFilter struct {
+	Set    []*httpHeaderValue `json:"set"`
+	Add    []*httpHeaderValue `json:"add"`
+	Remove []string           `json:"remove"`
+}
+
+type httpHeaderValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type httpRequestRedirectFilter struct {
+	Scheme     string `json:"scheme"`
+	Hostname   string `json:"hostname"`
+	Path       string `json:"path"`
+	StatusCode int    `json:"statusCode"`
+}
+
+type httpURLRewriteFilter struct {
+	Hostname string `json:"hostname"`
+	Path     string `json:"path"`
+}
+
+type httpRequestMirrorFilter struct {
+	BackendRef *httpBackendRef `json:"backendRef"`
+}
+
+type localObjectReference struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+}
+
+// filterConfig is the minimal shape of a namespaced Skipper FilterConfig
+// CRD, referenced via an ExtensionRef filter. Its spec is a raw eskip
+// filter expression, e.g. "filter1() -> filter2()".
+type filterConfig struct {
+	Metadata *metadata         `json:"metadata"`
+	Spec     *filterConfigSpec `json:"spec"`
+}
+
+type filterConfigSpec struct {
+	Filters string `json:"filters"`
+}
+
+// applyFilters translates a Gateway API HTTPRouteFilter chain into eskip
+// filters and prepends them to r.Filters, following applyBackend's
+// error-handling pattern: an invalid filter causes the whole route to be
+// dropped rather than the caller falling back to a partial chain.
+func applyFilters(ctx *gatewayContext, namespace string, filters []*httpRouteFilter, r *eskip.Route) error {
+	var chain []*eskip.Filter
+	for _, f := range filters {
+		switch f.Type {
+		case filterRequestHeaderModifier:
+			chain = append(chain, headerModifierFilters("Request", f.RequestHeaderModifier)...)
+		case filterResponseHeaderModifier:
+			chain = append(chain, headerModifierFilters("Response", f.ResponseHeaderModifier)...)
+		case filterRequestRedirect:
+			rf, err := requestRedirectFilter(f.RequestRedirect)
+			if err != nil {
+				return err
+			}
+
+			chain = append(chain, rf)
+			r.Filters = append(r.Filters, chain...)
+			r.Shunt = true
+			return nil
+		case filterURLRewrite:
+			chain = append(chain, urlRewriteFilters(f.URLRewrite)...)
+		case filterRequestMirror:
+			// Handled separately by mirrorFilters, which must run once per
+			// rule match rather than once per route: see its doc comment.
+		case filterExtensionRef:
+			ef, err := extensionRefFilters(ctx, namespace, f.ExtensionRef)
+			if err != nil {
+				return err
+			}
+
+			chain = append(chain, ef...)
+		default:
+			return fmt.Errorf("unsupported httproute filter type: %s", f.Type)
+		}
+	}
+
+	r.Filters = append(chain, r.Filters...)
+	return nil
+}
+
+func headerModifierFilters(direction string, h *httpHeaderFilter) []*eskip.Filter {
+	if h == nil {
+		return nil
+	}
+
+	var fs []*eskip.Filter
+	for _, kv := range h.Set {
+		fs = append(fs, &eskip.Filter{
+			Name: "set" + direction + "Header",
+			Args: []interface{}{kv.Name, kv.Value},
+		})
+	}
+
+	for _, kv := range h.Add {
+		fs = append(fs, &eskip.Filter{
+			Name: "append" + direction + "Header",
+			Args: []interface{}{kv.Name, kv.Value},
+		})
+	}
+
+	for _, name := range h.Remove {
+		fs = append(fs, &eskip.Filter{
+			Name: "drop" + direction + "Header",
+			Args: []interface{}{name},
+		})
+	}
+
+	return fs
+}
+
+func requestRedirectFilter(rr *httpRequestRedirectFilter) (*eskip.Filter, error) {
+	if rr == nil {
+		return nil, fmt.Errorf("requestRedirect filter without configuration")
+	}
+
+	statusCode := rr.StatusCode
+	if statusCode == 0 {
+		statusCode = 302
+	}
+
+	location := rr.Scheme
+	if location != "" {
+		location += "://" + rr.Hostname
+	} else {
+		location = rr.Hostname
+	}
+
+	location += rr.Path
+
+	return &eskip.Filter{
+		Name: "redirectTo",
+		Args: []interface{}{float64(statusCode), location},
+	}, nil
+}
+
+func urlRewriteFilters(rw *httpURLRewriteFilter) []*eskip.Filter {
+	if rw == nil {
+		return nil
+	}
+
+	var fs []*eskip.Filter
+	if rw.Path != "" {
+		fs = append(fs, &eskip.Filter{Name: "setPath", Args: []interface{}{rw.Path}})
+	}
+
+	if rw.Hostname != "" {
+		fs = append(fs, &eskip.Filter{Name: "setHost", Args: []interface{}{rw.Hostname}})
+	}
+
+	return fs
+}
+
+// mirrorFilters resolves every RequestMirror filter in filters into a
+// teeLoopback filter, to be attached to every primary route built for this
+// rule match, plus the single shunt route that catches the tee'd traffic.
+// It must be called once per rule match, before the match's primary
+// (per-backendRef) routes are built, with an id prefix unique to the match,
+// the same way
+// appendMirrorRoutes must be called once per routegroup route rather than
+// once per backend: calling requestMirrorFilter once per backendRef would
+// attach the same loopback name to multiple primaries while also emitting
+// one shunt route per backendRef, and since they'd all share that loopback
+// name, the resulting Tee-predicated routes would be ambiguous duplicates
+// of each other.
+func mirrorFilters(ctx *gatewayContext, namespace string, filters []*httpRouteFilter, shuntIDPrefix string) ([]*eskip.Filter, []*eskip.Route, error) {
+	var teeFilters []*eskip.Filter
+	var shuntRoutes []*eskip.Route
+
+	for i, f := range filters {
+		if f.Type != filterRequestMirror {
+			continue
+		}
+
+		tf, shunt, err := requestMirrorFilter(ctx, namespace, f.RequestMirror, fmt.Sprintf("%s_mirror_%d", shuntIDPrefix, i))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		teeFilters = append(teeFilters, tf)
+		if shunt != nil {
+			shuntRoutes = append(shuntRoutes, shunt)
+		}
+	}
+
+	return teeFilters, shuntRoutes, nil
+}
+
+// requestMirrorFilter resolves the mirror backendRef the same way a regular
+// backendRef is resolved (applyServiceBackend). When the backend resolves
+// to a single network address it returns an inline tee filter (RouteGroup
+// mirrors, by contrast, always use teeLoopback and a shunt route). Otherwise (the common case: a ClusterIP service with LB
+// endpoints) it returns a teeLoopback filter together with the shunt route
+// that must be added to the route list to catch it — a teeLoopback with no
+// matching Tee-predicated route tees the request into a void.
+func requestMirrorFilter(ctx *gatewayContext, namespace string, m *httpRequestMirrorFilter, shuntID string) (*eskip.Filter, *eskip.Route, error) {
+	if m == nil || m.BackendRef == nil {
+		return nil, nil, fmt.Errorf("requestMirror filter without a backendRef")
+	}
+
+	ns := namespace
+	if m.BackendRef.Namespace != "" {
+		ns = m.BackendRef.Namespace
+	}
+
+	be := &skipperBackend{
+		Type:        serviceBackend,
+		ServiceName: m.BackendRef.Name,
+		ServicePort: m.BackendRef.Port,
+	}
+
+	rgCtx := &routeGroupContext{
+		clusterState:   ctx.clusterState,
+		defaultFilters: ctx.defaultFilters,
+		routeGroup:     &routeGroupItem{Metadata: &metadata{Namespace: ns}},
+	}
+
+	dummy := &eskip.Route{}
+	if err := applyServiceBackend(rgCtx, be, dummy); err != nil {
+		return nil, nil, err
+	}
+
+	if dummy.Backend != "" {
+		return &eskip.Filter{Name: "tee", Args: []interface{}{dummy.Backend}}, nil, nil
+	}
+
+	// shuntID is already unique per rule match and mirror-filter index (see
+	// mirrorFilters), so folding it into the loopback name keeps two
+	// different rules (or two different matches) that both mirror to the
+	// same backend from sharing one Tee-predicated route.
+	loopbackName := "mirror_" + toSymbol(shuntID) + "_" + toSymbol(ns) + "_" + toSymbol(m.BackendRef.Name)
+
+	shunt := &eskip.Route{
+		Id:          shuntID,
+		Predicates:  []*eskip.Predicate{{Name: "Tee", Args: []interface{}{loopbackName}}},
+		BackendType: dummy.BackendType,
+		Backend:     dummy.Backend,
+		LBEndpoints: dummy.LBEndpoints,
+		LBAlgorithm: dummy.LBAlgorithm,
+	}
+
+	if err := applyDefaultFilters(rgCtx, m.BackendRef.Name, shunt); err != nil {
+		return nil, nil, err
+	}
+
+	return &eskip.Filter{Name: "teeLoopback", Args: []interface{}{loopbackName}}, shunt, nil
+}
+
+// extensionRefFilters looks up a namespaced Skipper FilterConfig CRD and
+// parses its spec as a raw eskip filter chain.
+func extensionRefFilters(ctx *gatewayContext, namespace string, ref *localObjectReference) ([]*eskip.Filter, error) {
+	if ref == nil || ref.Kind != "FilterConfig" {
+		return nil, fmt.Errorf("unsupported extensionRef: %v", ref)
+	}
+
+	fc := ctx.clusterState.getFilterConfig(namespace, ref.Name)
+	if fc == nil || fc.Spec == nil {
+		return nil, fmt.Errorf("filterconfig not found: %s/%s", namespace, ref.Name)
+	}
+
+	return eskip.ParseFilters(strings.TrimSpace(fc.Spec.Filters))
+}
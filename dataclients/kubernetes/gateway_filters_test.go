@@ -0,0 +1,62 @@
+package kubernetes
+
+import "testing"
+
+func testMirrorClusterState() *clusterState {
+	return &clusterState{
+		services: map[string]*service{
+			"ns/svc": {
+				Meta: &metadata{Namespace: "ns", Name: "svc"},
+				Spec: &serviceSpec{
+					Type:      "ClusterIP",
+					ClusterIP: "10.1.0.1",
+					Ports:     []*servicePort{{Port: 80, TargetPort: 8080}},
+				},
+			},
+		},
+		endpoints: map[string]*endpointItem{
+			"ns/svc": {
+				Meta: &metadata{Namespace: "ns", Name: "svc"},
+				Subsets: []*endpointSubset{{
+					Addresses: []*endpointAddress{{IP: "10.2.0.1"}, {IP: "10.2.0.2"}},
+					Ports:     []*endpointPort{{Port: 8080}},
+				}},
+			},
+		},
+	}
+}
+
+func mirrorBackendRef() *httpRequestMirrorFilter {
+	return &httpRequestMirrorFilter{BackendRef: &httpBackendRef{Name: "svc", Port: 80}}
+}
+
+// TestRequestMirrorFilterUniqueLoopbackPerShuntID checks that two calls
+// mirroring to the same backend under different shuntIDs (as happens for
+// two different rule matches, or two different rules, in the same
+// HTTPRoute) get distinct loopback names, so their Tee-predicated shunt
+// routes don't collide (see requestMirrorFilter's doc comment).
+func TestRequestMirrorFilterUniqueLoopbackPerShuntID(t *testing.T) {
+	ctx := &gatewayContext{clusterState: testMirrorClusterState()}
+
+	f1, shunt1, err := requestMirrorFilter(ctx, "ns", mirrorBackendRef(), "match-a_mirror_0")
+	if err != nil {
+		t.Fatalf("requestMirrorFilter() error = %v", err)
+	}
+
+	f2, shunt2, err := requestMirrorFilter(ctx, "ns", mirrorBackendRef(), "match-b_mirror_0")
+	if err != nil {
+		t.Fatalf("requestMirrorFilter() error = %v", err)
+	}
+
+	if shunt1 == nil || shunt2 == nil {
+		t.Fatalf("expected both mirror calls to resolve to LB endpoints and return a shunt route, got %v, %v", shunt1, shunt2)
+	}
+
+	if f1.Args[0] == f2.Args[0] {
+		t.Fatalf("expected distinct loopback names for distinct shuntIDs, both got: %v", f1.Args[0])
+	}
+
+	if shunt1.Predicates[0].Args[0] != f1.Args[0] || shunt2.Predicates[0].Args[0] != f2.Args[0] {
+		t.Fatalf("expected each shunt route's Tee predicate to match its own teeLoopback filter")
+	}
+}
@@ -0,0 +1,264 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestMatchingGatewayClasses(t *testing.T) {
+	classes := []*gatewayClass{
+		{
+			Metadata: &metadata{Name: "skipper"},
+			Spec:     &gatewayClassSpec{ControllerName: skipperGatewayControllerName},
+		},
+		{
+			Metadata: &metadata{Name: "other-controller"},
+			Spec:     &gatewayClassSpec{ControllerName: "example.com/other"},
+		},
+	}
+
+	got := matchingGatewayClasses(classes)
+	if !got["skipper"] || got["other-controller"] {
+		t.Fatalf("expected only the skipper-controlled class to match, got: %v", got)
+	}
+}
+
+func TestReferenceAllowed(t *testing.T) {
+	grants := []*referenceGrant{
+		{
+			Metadata: &metadata{Namespace: "backend-ns"},
+			Spec: &referenceGrantSpec{
+				From: []*referenceGrantFrom{{Kind: "HTTPRoute", Namespace: "route-ns"}},
+				To:   []*referenceGrantTo{{Kind: "Service", Name: "my-svc"}},
+			},
+		},
+	}
+
+	for _, tt := range []struct {
+		title                            string
+		fromNamespace, toNs, backendName string
+		want                             bool
+	}{
+		{"same namespace is always allowed", "route-ns", "route-ns", "my-svc", true},
+		{"granted cross-namespace reference", "route-ns", "backend-ns", "my-svc", true},
+		{"ungranted service name", "route-ns", "backend-ns", "other-svc", false},
+		{"ungranted source namespace", "other-ns", "backend-ns", "my-svc", false},
+	} {
+		t.Run(tt.title, func(t *testing.T) {
+			if got := referenceAllowed(grants, tt.fromNamespace, tt.toNs, tt.backendName); got != tt.want {
+				t.Errorf("referenceAllowed() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToBackendReferences(t *testing.T) {
+	refs := []*httpBackendRef{
+		{Name: "primary", Weight: 80},
+		{Name: "canary", Weight: 20},
+	}
+
+	got := toBackendReferences(refs)
+	want := []*backendReference{
+		{BackendName: "primary", Weight: 80},
+		{BackendName: "canary", Weight: 20},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("toBackendReferences() = %#v, want %#v", got, want)
+	}
+}
+
+func TestApplyHTTPRouteMatch(t *testing.T) {
+	m := &httpRouteMatch{
+		Path:   &httpPathMatch{Type: "PathPrefix", Value: "/api"},
+		Method: "POST",
+		Headers: []*httpHeaderMatch{
+			{Name: "X-Test", Value: "1"},
+		},
+	}
+
+	r := &eskip.Route{}
+	applyHTTPRouteMatch(r, m)
+
+	var havePathSubtree, haveMethod, haveHeader bool
+	for _, p := range r.Predicates {
+		switch p.Name {
+		case "PathSubtree":
+			havePathSubtree = true
+		case "Method":
+			haveMethod = true
+		case "Header":
+			haveHeader = true
+		}
+	}
+
+	if !havePathSubtree || !haveMethod || !haveHeader {
+		t.Fatalf("expected PathSubtree, Method and Header predicates, got: %v", r.Predicates)
+	}
+}
+
+// TestGatewayAPIConvertEndToEnd drives a Gateway with one matching HTTPRoute
+// through convert() and checks the resulting eskip routes: a Host predicate
+// derived from the listener, a path match, a header-modifier filter, an
+// LB backend resolved from the cluster state's services/endpoints, and a
+// Traffic predicate on the first (80%) split of a two-way weighted
+// backendRef; the last split gets the remainder and no Traffic predicate.
+func TestGatewayAPIConvertEndToEnd(t *testing.T) {
+	g := newGatewayAPI(Options{})
+
+	state := &clusterState{
+		gatewayClasses: []*gatewayClass{
+			{
+				Metadata: &metadata{Name: "skipper"},
+				Spec:     &gatewayClassSpec{ControllerName: skipperGatewayControllerName},
+			},
+		},
+		gateways: []*gatewayItem{
+			{
+				Metadata: &metadata{Namespace: "default", Name: "gw"},
+				Spec: &gatewaySpec{
+					GatewayClassName: "skipper",
+					Listeners: []*gatewayListener{
+						{Name: "http", Hostname: "example.org", Port: 80, Protocol: "HTTP"},
+					},
+				},
+			},
+		},
+		httpRoutes: []*httpRouteItem{
+			{
+				Metadata: &metadata{Namespace: "default", Name: "hr"},
+				Spec: &httpRouteSpec{
+					ParentRefs: []*parentReference{{Namespace: "default", Name: "gw"}},
+					Rules: []*httpRouteRule{
+						{
+							Matches: []*httpRouteMatch{
+								{Path: &httpPathMatch{Type: "PathPrefix", Value: "/api"}},
+							},
+							Filters: []*httpRouteFilter{
+								{
+									Type: filterRequestHeaderModifier,
+									RequestHeaderModifier: &httpHeaderFilter{
+										Set: []*httpHeaderValue{{Name: "X-Added", Value: "1"}},
+									},
+								},
+							},
+							BackendRefs: []*httpBackendRef{
+								{Name: "primary", Port: 80, Weight: 80},
+								{Name: "canary", Port: 80, Weight: 20},
+							},
+						},
+					},
+				},
+			},
+		},
+		services: map[string]*service{
+			"default/primary": {
+				Meta: &metadata{Namespace: "default", Name: "primary"},
+				Spec: &serviceSpec{Type: "ClusterIP", ClusterIP: "10.0.0.1", Ports: []*servicePort{{Port: 80, TargetPort: 8080}}},
+			},
+			"default/canary": {
+				Meta: &metadata{Namespace: "default", Name: "canary"},
+				Spec: &serviceSpec{Type: "ClusterIP", ClusterIP: "10.0.0.2", Ports: []*servicePort{{Port: 80, TargetPort: 8080}}},
+			},
+		},
+		endpoints: map[string]*endpointItem{
+			"default/primary": {
+				Meta: &metadata{Namespace: "default", Name: "primary"},
+				Subsets: []*endpointSubset{{
+					Addresses: []*endpointAddress{{IP: "10.1.0.1"}},
+					Ports:     []*endpointPort{{Port: 8080}},
+				}},
+			},
+			"default/canary": {
+				Meta: &metadata{Namespace: "default", Name: "canary"},
+				Subsets: []*endpointSubset{{
+					Addresses: []*endpointAddress{{IP: "10.1.0.2"}},
+					Ports:     []*endpointPort{{Port: 8080}},
+				}},
+			},
+		},
+	}
+
+	routes, err := g.convert(state, defaultFilters{})
+	if err != nil {
+		t.Fatalf("convert() error = %v", err)
+	}
+
+	if len(routes) != 2 {
+		t.Fatalf("expected one route per backendRef, got %d: %v", len(routes), routes)
+	}
+
+	var sawTraffic, sawPrimary bool
+	for _, r := range routes {
+		var havePathSubtree, haveHeaderFilter bool
+		for _, p := range r.Predicates {
+			switch p.Name {
+			case "PathSubtree":
+				havePathSubtree = true
+			case "Traffic":
+				sawTraffic = true
+			}
+		}
+
+		if !havePathSubtree {
+			t.Fatalf("expected a PathSubtree predicate on every route, got: %v", r.Predicates)
+		}
+
+		for _, f := range r.Filters {
+			if f.Name == "setRequestHeader" {
+				haveHeaderFilter = true
+			}
+		}
+
+		if !haveHeaderFilter {
+			t.Fatalf("expected the RequestHeaderModifier filter on every route, got: %v", r.Filters)
+		}
+
+		if r.BackendType != eskip.LBBackend || len(r.LBEndpoints) != 1 {
+			t.Fatalf("expected a resolved LB backend, got: %#v", r)
+		}
+
+		if r.LBEndpoints[0] == "http://10.1.0.1:8080" {
+			sawPrimary = true
+		}
+	}
+
+	if !sawTraffic {
+		t.Fatalf("expected a Traffic predicate on the first weighted backendRef, got: %v", routes)
+	}
+
+	if !sawPrimary {
+		t.Fatalf("expected the primary backendRef's endpoint to be resolved, got: %v", routes)
+	}
+}
+
+func TestGatewayAPIConvertSkipsOtherControllers(t *testing.T) {
+	g := newGatewayAPI(Options{})
+
+	state := &clusterState{
+		gatewayClasses: []*gatewayClass{
+			{
+				Metadata: &metadata{Name: "other"},
+				Spec:     &gatewayClassSpec{ControllerName: "example.com/other"},
+			},
+		},
+		gateways: []*gatewayItem{
+			{
+				Metadata: &metadata{Namespace: "default", Name: "gw"},
+				Spec:     &gatewaySpec{GatewayClassName: "other"},
+			},
+		},
+	}
+
+	routes, err := g.convert(state, defaultFilters{})
+	if err != nil {
+		t.Fatalf("convert() error = %v", err)
+	}
+
+	if len(routes) != 0 {
+		t.Fatalf("expected no routes for a Gateway not owned by skipper's controller, got %d", len(routes))
+	}
+}
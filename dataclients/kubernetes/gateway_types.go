@@ -0,0 +1,160 @@
+package kubernetes
+
+// This file holds the minimal set of Gateway API CRD types needed by the
+// gatewayAPI dataclient to compute eskip routes. It mirrors the shape of
+// the upstream gateway.networking.k8s.io types closely enough for our
+// conversion purposes, without pulling in the full Gateway API client-go
+// bindings.
+
+type gatewayClassList struct {
+	Items []*gatewayClass `json:"items"`
+}
+
+type gatewayClass struct {
+	Metadata *metadata         `json:"metadata"`
+	Spec     *gatewayClassSpec `json:"spec"`
+}
+
+type gatewayClassSpec struct {
+	ControllerName string `json:"controllerName"`
+}
+
+type gatewayList struct {
+	Items []*gatewayItem `json:"items"`
+}
+
+type gatewayItem struct {
+	Metadata *metadata    `json:"metadata"`
+	Spec     *gatewaySpec `json:"spec"`
+}
+
+type gatewaySpec struct {
+	GatewayClassName string             `json:"gatewayClassName"`
+	Listeners        []*gatewayListener `json:"listeners"`
+}
+
+type gatewayListener struct {
+	Name     string `json:"name"`
+	Hostname string `json:"hostname"`
+	Port     int    `json:"port"`
+	Protocol string `json:"protocol"`
+}
+
+type httpRouteList struct {
+	Items []*httpRouteItem `json:"items"`
+}
+
+type httpRouteItem struct {
+	Metadata *metadata      `json:"metadata"`
+	Spec     *httpRouteSpec `json:"spec"`
+}
+
+type httpRouteSpec struct {
+	ParentRefs []*parentReference `json:"parentRefs"`
+	Hostnames  []string           `json:"hostnames"`
+	Rules      []*httpRouteRule   `json:"rules"`
+}
+
+type parentReference struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type httpRouteRule struct {
+	Matches     []*httpRouteMatch  `json:"matches"`
+	Filters     []*httpRouteFilter `json:"filters"`
+	BackendRefs []*httpBackendRef  `json:"backendRefs"`
+}
+
+type httpRouteMatch struct {
+	Path        *httpPathMatch         `json:"path"`
+	Method      string                 `json:"method"`
+	Headers     []*httpHeaderMatch     `json:"headers"`
+	QueryParams []*httpQueryParamMatch `json:"queryParams"`
+}
+
+type httpPathMatch struct {
+	Type  string `json:"type"` // Exact, PathPrefix, RegularExpression
+	Value string `json:"value"`
+}
+
+type httpHeaderMatch struct {
+	Type  string `json:"type"` // Exact, RegularExpression
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type httpQueryParamMatch struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type httpBackendRef struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Port      int    `json:"port"`
+	Weight    int    `json:"weight"`
+}
+
+// tlsRouteItem and tcpRouteItem are fetched and stored on clusterState like
+// the other Gateway API kinds, so that a future conversion pass can read
+// them; Skipper does not terminate TLS/TCP itself, so neither is converted
+// into eskip routes yet.
+type tlsRouteList struct {
+	Items []*tlsRouteItem `json:"items"`
+}
+
+type tlsRouteItem struct {
+	Metadata *metadata     `json:"metadata"`
+	Spec     *tlsRouteSpec `json:"spec"`
+}
+
+type tlsRouteSpec struct {
+	ParentRefs  []*parentReference `json:"parentRefs"`
+	Hostnames   []string           `json:"hostnames"`
+	BackendRefs []*httpBackendRef  `json:"backendRefs"`
+}
+
+type tcpRouteList struct {
+	Items []*tcpRouteItem `json:"items"`
+}
+
+type tcpRouteItem struct {
+	Metadata *metadata     `json:"metadata"`
+	Spec     *tcpRouteSpec `json:"spec"`
+}
+
+type tcpRouteSpec struct {
+	ParentRefs  []*parentReference `json:"parentRefs"`
+	BackendRefs []*httpBackendRef  `json:"backendRefs"`
+}
+
+type referenceGrantList struct {
+	Items []*referenceGrant `json:"items"`
+}
+
+// referenceGrant allows a route in fromNamespace to reference a backend in
+// the namespace the grant lives in, as long as one of its From/To entries
+// matches.
+type referenceGrant struct {
+	Metadata *metadata           `json:"metadata"`
+	Spec     *referenceGrantSpec `json:"spec"`
+}
+
+type referenceGrantSpec struct {
+	From []*referenceGrantFrom `json:"from"`
+	To   []*referenceGrantTo   `json:"to"`
+}
+
+type referenceGrantFrom struct {
+	Group     string `json:"group"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+}
+
+type referenceGrantTo struct {
+	Group string `json:"group"`
+	Kind  string `json:"kind"`
+	Name  string `json:"name"`
+}
@@ -23,7 +23,9 @@ import (
 // - document the behavior of the weight implementation
 
 type routeGroups struct {
-	options Options
+	options     Options
+	status      *statusWriter
+	diagnostics *diagnostics
 }
 
 type routeGroupContext struct {
@@ -39,6 +41,7 @@ type routeGroupContext struct {
 	httpsRedirectCode     int
 	backendsByName        map[string]*skipperBackend
 	defaultBackendTraffic map[string]float64
+	result                *TransformResult
 }
 
 type routeContext struct {
@@ -54,8 +57,38 @@ func newRouteGroups(o Options) *routeGroups {
 	return &routeGroups{options: o}
 }
 
+// routeIDs returns the ids of the given routes, for reporting on the
+// RouteGroup's status subresource.
+func routeIDs(routes []*eskip.Route) []string {
+	ids := make([]string, len(routes))
+	for i, r := range routes {
+		ids[i] = r.Id
+	}
+
+	return ids
+}
+
+// reportStatus queues a status update for rg, if a statusWriter is
+// configured. Skipper works fine without one: the RouteGroup just doesn't
+// get a status subresource written back.
+func (r *routeGroups) reportStatus(rg *routeGroupItem, status *resourceStatus) {
+	if r.status == nil {
+		return
+	}
+
+	status.ObservedGeneration = rg.Metadata.Generation
+	path := fmt.Sprintf(
+		"/apis/zalando.org/v1/namespaces/%s/routegroups/%s/status",
+		namespaceString(rg.Metadata.Namespace),
+		rg.Metadata.Name,
+	)
+
+	r.status.update("routegroup", namespaceString(rg.Metadata.Namespace), rg.Metadata.Name, path, status)
+}
+
 func invalidBackendRef(rg *routeGroupItem, name string) error {
-	return fmt.Errorf(
+	return newTransformError(
+		reasonInvalidBackendRef,
 		"invalid backend reference in routegroup/%s/%s: %s",
 		namespaceString(rg.Metadata.Namespace),
 		rg.Metadata.Name,
@@ -64,7 +97,8 @@ func invalidBackendRef(rg *routeGroupItem, name string) error {
 }
 
 func notSupportedServiceType(s *service) error {
-	return fmt.Errorf(
+	return newTransformError(
+		reasonUnsupportedServiceType,
 		"not supported service type in service/%s/%s: %s",
 		namespaceString(s.Meta.Namespace),
 		s.Meta.Name,
@@ -165,6 +199,98 @@ func calculateTraffic(b []*backendReference) map[string]float64 {
 	return traffic
 }
 
+// nonMirrorBackends filters out backend references marked as mirrors, since
+// calculateTraffic only distributes traffic among the primary backends; a
+// mirror's weight is reinterpreted as a sampling percentage instead of a
+// regular traffic share.
+func nonMirrorBackends(b []*backendReference) []*backendReference {
+	var out []*backendReference
+	for _, bi := range b {
+		if !bi.Mirror {
+			out = append(out, bi)
+		}
+	}
+
+	return out
+}
+
+// resolvableBackends filters out backend references that don't resolve to a
+// backend in ctx.backendsByName. Such a reference never produces a route
+// (the backendIndex loops in implicitGroupRoutes/explicitGroupRoutes drop it
+// via ctx.result.addDropped), so it must also be excluded before
+// calculateTraffic runs: including it there would leave its share of
+// traffic unaccounted for, instead of redistributing it among the backend
+// references that do route.
+func resolvableBackends(ctx *routeGroupContext, b []*backendReference) []*backendReference {
+	var out []*backendReference
+	for _, bi := range b {
+		if _, ok := ctx.backendsByName[bi.BackendName]; ok {
+			out = append(out, bi)
+		}
+	}
+
+	return out
+}
+
+// appendMirrorRoutes emits, for every backend reference marked as a mirror,
+// a teeLoopback filter on every one of the route's primary routes and a
+// single shunt route that forwards the tee'd traffic to the mirror's
+// endpoints. It must be called once per route-group route, after all of the
+// route's primary (non-mirror) backend routes have been built: calling it
+// once per primary backend would attach the same loopback name to multiple
+// primaries while also emitting one shunt route per primary, and since they
+// all share that loopback name, the resulting Tee-predicated routes would
+// be ambiguous duplicates of each other. The mirror's weight is
+// reinterpreted as a 0-100 sampling percentage, implemented as a Traffic
+// predicate on the shunt route.
+//
+// The loopback name is derived from primaries[0].Id as well as the backend
+// name, not just the backend name: two different routes in the same route
+// group (or the same backend mirrored from two different routes) would
+// otherwise share one Tee-predicated route, which is the same ambiguous
+// duplicate problem one level up.
+func appendMirrorRoutes(ctx *routeGroupContext, routes []*eskip.Route, primaries []*eskip.Route, backendRefs []*backendReference) ([]*eskip.Route, error) {
+	if len(primaries) == 0 {
+		return routes, nil
+	}
+
+	for i, bref := range backendRefs {
+		if !bref.Mirror {
+			continue
+		}
+
+		be, ok := ctx.backendsByName[bref.BackendName]
+		if !ok {
+			return routes, invalidBackendRef(ctx.routeGroup, bref.BackendName)
+		}
+
+		loopbackName := fmt.Sprintf("mirror-%s-%s", primaries[0].Id, bref.BackendName)
+		for _, primary := range primaries {
+			primary.Filters = append(primary.Filters, &eskip.Filter{
+				Name: "teeLoopback",
+				Args: []interface{}{loopbackName},
+			})
+		}
+
+		mr := &eskip.Route{
+			Id:         fmt.Sprintf("%s_mirror_%d", primaries[0].Id, i),
+			Predicates: []*eskip.Predicate{{Name: "Tee", Args: []interface{}{loopbackName}}},
+		}
+
+		if err := applyBackend(ctx, be, mr); err != nil {
+			return routes, err
+		}
+
+		if sample := float64(bref.Weight) / 100; sample < 1 {
+			mr.Predicates = appendPredicate(mr.Predicates, "Traffic", sample)
+		}
+
+		routes = append(routes, mr)
+	}
+
+	return routes, nil
+}
+
 func applyDefaultFilters(ctx *routeGroupContext, serviceName string, r *eskip.Route) error {
 	f, err := ctx.defaultFilters.getNamed(ctx.routeGroup.Metadata.Namespace, serviceName)
 	if err != nil {
@@ -178,7 +304,8 @@ func applyDefaultFilters(ctx *routeGroupContext, serviceName string, r *eskip.Ro
 
 func getBackendService(ctx *routeGroupContext, backend *skipperBackend) (*service, error) {
 	if backend.ServiceName == "" || backend.ServicePort <= 0 {
-		return nil, fmt.Errorf(
+		return nil, newTransformError(
+			reasonInvalidBackendRef,
 			"invalid service backend in routegroup/%s/%s: %s:%d",
 			namespaceString(ctx.routeGroup.Metadata.Namespace),
 			ctx.routeGroup.Metadata.Name,
@@ -209,7 +336,8 @@ func getBackendService(ctx *routeGroupContext, backend *skipperBackend) (*servic
 		}
 	}
 
-	return nil, fmt.Errorf(
+	return nil, newTransformError(
+		reasonServicePortNotFound,
 		"service port not found for routegroup/%s/%s: %d",
 		namespaceString(ctx.routeGroup.Metadata.Namespace),
 		ctx.routeGroup.Metadata.Name,
@@ -229,10 +357,13 @@ func applyServiceBackend(ctx *routeGroupContext, backend *skipperBackend, r *esk
 
 	targetPort, ok := s.getTargetPortByValue(backend.ServicePort)
 	if !ok {
-		// TODO: log fallback
+		log.Errorf(
+			"Target port not found for service/%s/%s:%d, falling back to the ClusterIP address.",
+			namespaceString(ctx.routeGroup.Metadata.Namespace), s.Meta.Name, backend.ServicePort,
+		)
 		r.BackendType = eskip.NetworkBackend
 		r.Backend = createClusterIPBackend(s, backend)
-		return err
+		return nil
 	}
 
 	eps := ctx.clusterState.getEndpointsByTarget(
@@ -242,7 +373,14 @@ func applyServiceBackend(ctx *routeGroupContext, backend *skipperBackend, r *esk
 	)
 
 	if len(eps) == 0 {
-		// TODO: log fallback
+		log.Errorf(
+			"%v, falling back to the ClusterIP address.",
+			newTransformError(
+				reasonEndpointsMissing,
+				"no ready endpoints for service/%s/%s:%d",
+				namespaceString(ctx.routeGroup.Metadata.Namespace), s.Meta.Name, targetPort,
+			),
+		)
 		r.BackendType = eskip.NetworkBackend
 		r.Backend = createClusterIPBackend(s, backend)
 		return nil
@@ -255,6 +393,8 @@ func applyServiceBackend(ctx *routeGroupContext, backend *skipperBackend, r *esk
 		r.LBAlgorithm = backend.Algorithm.String()
 	}
 
+	applyAffinity(backend, r)
+
 	return nil
 }
 
@@ -275,6 +415,7 @@ func applyBackend(ctx *routeGroupContext, backend *skipperBackend, r *eskip.Rout
 		r.LBEndpoints = backend.Endpoints
 		r.LBAlgorithm = defaultLoadBalancerAlgorithm
 		r.LBAlgorithm = backend.Algorithm.String()
+		applyAffinity(backend, r)
 	default:
 		return notImplemented("backend type", r.BackendType)
 	}
@@ -282,6 +423,75 @@ func applyBackend(ctx *routeGroupContext, backend *skipperBackend, r *eskip.Rout
 	return nil
 }
 
+// Affinity modes supported by skipperBackend.Affinity.
+const (
+	affinityModeCookie         = "cookie"
+	affinityModeHeader         = "header"
+	affinityModeSourceIP       = "sourceIP"
+	affinityModeConsistentHash = "consistentHash"
+)
+
+const defaultAffinityCookieName = "skipper-affinity"
+
+// applyAffinity switches a backend to consistent-hash load balancing and
+// prepends the hash-key filter matching its affinity mode.
+//
+// In cookie mode the hash key is the value of the request's own affinity
+// cookie, and a setResponseCookie filter is added with that same
+// "${request.cookie.<name>}" expression as its value. No hash key is
+// generated for a client: clients that send no affinity cookie all hash on
+// the same empty key, and so are sent to the same endpoint.
+func applyAffinity(backend *skipperBackend, r *eskip.Route) {
+	if backend.Affinity == nil {
+		return
+	}
+
+	r.LBAlgorithm = "consistentHash"
+
+	var fs []*eskip.Filter
+	switch backend.Affinity.Mode {
+	case affinityModeHeader:
+		fs = append(fs, &eskip.Filter{
+			Name: "consistentHashKey",
+			Args: []interface{}{fmt.Sprintf("${request.header.%s}", backend.Affinity.HeaderName)},
+		})
+	case affinityModeSourceIP:
+		fs = append(fs, &eskip.Filter{
+			Name: "consistentHashKey",
+			Args: []interface{}{"${request.remote_host}"},
+		})
+	case affinityModeCookie:
+		cookieName := backend.Affinity.CookieName
+		if cookieName == "" {
+			cookieName = defaultAffinityCookieName
+		}
+
+		key := cookieHashKey(cookieName)
+		fs = append(fs,
+			&eskip.Filter{
+				Name: "consistentHashKey",
+				Args: []interface{}{key},
+			},
+			&eskip.Filter{
+				Name: "setResponseCookie",
+				Args: []interface{}{cookieName, key},
+			},
+		)
+	case affinityModeConsistentHash:
+		fs = append(fs, &eskip.Filter{
+			Name: "consistentHashKey",
+			Args: []interface{}{backend.Affinity.Key},
+		})
+	}
+
+	r.Filters = append(fs, r.Filters...)
+}
+
+// cookieHashKey builds the hash-key expression for the named cookie.
+func cookieHashKey(cookieName string) string {
+	return fmt.Sprintf("${request.cookie.%s}", cookieName)
+}
+
 func appendPredicate(p []*eskip.Predicate, name string, args ...interface{}) []*eskip.Predicate {
 	return append(p, &eskip.Predicate{
 		Name: name,
@@ -331,10 +541,11 @@ func appendHTTPSRedirect(ctx *routeGroupContext, routes []*eskip.Route, current
 func implicitGroupRoutes(ctx *routeGroupContext) ([]*eskip.Route, error) {
 	rg := ctx.routeGroup
 	if len(rg.Spec.DefaultBackends) == 0 {
-		return nil, fmt.Errorf("missing route spec for route group: %s", rg.Metadata.Name)
+		return nil, newTransformError(reasonMissingBackends, "missing route spec for route group: %s", rg.Metadata.Name)
 	}
 
 	var routes []*eskip.Route
+	var primaries []*eskip.Route
 	for backendIndex, beref := range rg.Spec.DefaultBackends {
 		if beref == nil {
 			log.Errorf(
@@ -346,21 +557,27 @@ func implicitGroupRoutes(ctx *routeGroupContext) ([]*eskip.Route, error) {
 			continue
 		}
 
+		if beref.Mirror {
+			continue
+		}
+
+		rid := crdRouteID(rg.Metadata, "all", 0, backendIndex)
+
 		be, ok := ctx.backendsByName[beref.BackendName]
 		if !ok {
-			return nil, invalidBackendRef(rg, beref.BackendName)
+			ctx.result.addDropped(rg, rid, invalidBackendRef(rg, beref.BackendName))
+			continue
 		}
 
-		rid := crdRouteID(rg.Metadata, "all", 0, backendIndex)
 		ri := &eskip.Route{Id: rid}
 		if err := applyBackend(ctx, be, ri); err != nil {
-			// TODO: log only?
-			return nil, err
+			ctx.result.addDropped(rg, rid, err)
+			continue
 		}
 
 		if be.Type == serviceBackend {
 			if err := applyDefaultFilters(ctx, be.ServiceName, ri); err != nil {
-				log.Errorf("Failed to retrieve default filters: %v.", err)
+				log.Errorf("Failed to retrieve default filters: %v.", newTransformError(reasonDefaultFiltersFailed, "%v", err))
 			}
 		}
 
@@ -376,6 +593,13 @@ func implicitGroupRoutes(ctx *routeGroupContext) ([]*eskip.Route, error) {
 		routes = append(routes, ri)
 		routes = appendEastWest(ctx, routes, ri)
 		routes = appendHTTPSRedirect(ctx, routes, ri)
+		ctx.result.Accepted = append(ctx.result.Accepted, rid)
+		primaries = append(primaries, ri)
+	}
+
+	var err error
+	if routes, err = appendMirrorRoutes(ctx, routes, primaries, rg.Spec.DefaultBackends); err != nil {
+		ctx.result.addDropped(rg, crdRouteID(rg.Metadata, "all", 0, 0), err)
 	}
 
 	return routes, nil
@@ -409,7 +633,7 @@ func transformExplicitGroupRoute(ctx *routeContext) (*eskip.Route, error) {
 	for _, pi := range gr.Predicates {
 		ppi, err := eskip.ParsePredicates(pi)
 		if err != nil {
-			return nil, err
+			return nil, newTransformError(reasonPredicateParseError, "%v", err)
 		}
 
 		r.Predicates = append(r.Predicates, ppi...)
@@ -419,7 +643,7 @@ func transformExplicitGroupRoute(ctx *routeContext) (*eskip.Route, error) {
 	for _, fi := range gr.Filters {
 		ffi, err := eskip.ParseFilters(fi)
 		if err != nil {
-			return nil, err
+			return nil, newTransformError(reasonFilterParseError, "%v", err)
 		}
 
 		f = append(f, ffi...)
@@ -451,29 +675,39 @@ func explicitGroupRoutes(ctx *routeGroupContext) ([]*eskip.Route, error) {
 		backendTraffic := ctx.defaultBackendTraffic
 		if len(rgr.Backends) != 0 {
 			backendRefs = rgr.Backends
-			backendTraffic = calculateTraffic(rgr.Backends)
+			backendTraffic = calculateTraffic(resolvableBackends(ctx, nonMirrorBackends(rgr.Backends)))
 		}
 
-		// TODO: handling errors. If we consider the route groups independent, then
-		// it should be enough to just log them.
+		// a bad backend ref or an unparsable predicate/filter on one route only
+		// drops that route; it's reported via ctx.result instead of aborting
+		// the rest of the route group.
 
+		var primaries []*eskip.Route
 		for method := range uniqueMethods {
 			for backendIndex, bref := range backendRefs {
+				if bref.Mirror {
+					continue
+				}
+
+				rid := crdRouteID(rg.Metadata, method, routeIndex, backendIndex)
+
 				be, ok := ctx.backendsByName[bref.BackendName]
 				if !ok {
-					return nil, invalidBackendRef(rg, bref.BackendName)
+					ctx.result.addDropped(rg, rid, invalidBackendRef(rg, bref.BackendName))
+					continue
 				}
 
 				r, err := transformExplicitGroupRoute(&routeContext{
 					group:      ctx,
 					groupRoute: rgr,
-					id:         crdRouteID(rg.Metadata, method, routeIndex, backendIndex),
+					id:         rid,
 					weight:     bref.Weight,
 					method:     method,
 					backend:    be,
 				})
 				if err != nil {
-					return nil, err
+					ctx.result.addDropped(rg, rid, err)
+					continue
 				}
 
 				if traffic := backendTraffic[bref.BackendName]; traffic < 1 {
@@ -482,10 +716,17 @@ func explicitGroupRoutes(ctx *routeGroupContext) ([]*eskip.Route, error) {
 
 				storeHostRoute(ctx, r)
 				routes = append(routes, r)
+				ctx.result.Accepted = append(ctx.result.Accepted, rid)
 				routes = appendEastWest(ctx, routes, r)
 				routes = appendHTTPSRedirect(ctx, routes, r)
+				primaries = append(primaries, r)
 			}
 		}
+
+		var err error
+		if routes, err = appendMirrorRoutes(ctx, routes, primaries, backendRefs); err != nil {
+			ctx.result.addDropped(rg, crdRouteID(rg.Metadata, "all", routeIndex, 0), err)
+		}
 	}
 
 	return routes, nil
@@ -494,10 +735,10 @@ func explicitGroupRoutes(ctx *routeGroupContext) ([]*eskip.Route, error) {
 func transformRouteGroup(ctx *routeGroupContext) ([]*eskip.Route, error) {
 	rg := ctx.routeGroup
 	if len(rg.Spec.Backends) == 0 {
-		return nil, fmt.Errorf("missing backend for route group: %s", rg.Metadata.Name)
+		return nil, newTransformError(reasonMissingBackends, "missing backend for route group: %s", rg.Metadata.Name)
 	}
 
-	ctx.defaultBackendTraffic = calculateTraffic(rg.Spec.DefaultBackends)
+	ctx.defaultBackendTraffic = calculateTraffic(resolvableBackends(ctx, nonMirrorBackends(rg.Spec.DefaultBackends)))
 	if len(rg.Spec.Routes) == 0 {
 		return implicitGroupRoutes(ctx)
 	}
@@ -505,9 +746,10 @@ func transformRouteGroup(ctx *routeGroupContext) ([]*eskip.Route, error) {
 	return explicitGroupRoutes(ctx)
 }
 
-func (r *routeGroups) convert(s *clusterState, df defaultFilters) ([]*eskip.Route, error) {
+func (r *routeGroups) convert(s *clusterState, df defaultFilters) ([]*eskip.Route, *TransformResult, error) {
 	var rs []*eskip.Route
 
+	result := &TransformResult{}
 	hostRoutes := make(map[string][]*eskip.Route)
 	var missingName, missingSpec bool
 	for _, rg := range s.routeGroups {
@@ -533,14 +775,38 @@ func (r *routeGroups) convert(s *clusterState, df defaultFilters) ([]*eskip.Rout
 			provideHTTPSRedirect: r.options.ProvideHTTPSRedirect,
 			httpsRedirectCode:    r.options.HTTPSRedirectCode,
 			backendsByName:       mapBackends(rg.Spec.Backends),
+			result:               result,
 		}
 
+		droppedBefore := len(result.Dropped)
 		ri, err := transformRouteGroup(ctx)
 		if err != nil {
 			log.Errorf("Error transforming route group %s: %v.", rg.Metadata.Name, err)
+			result.addDropped(rg, "", err)
+			r.reportStatus(rg, &resourceStatus{Reason: statusReasonConversionError, Message: err.Error()})
 			continue
 		}
 
+		status := &resourceStatus{
+			Accepted: true,
+			Reason:   statusReasonAccepted,
+			Hosts:    rg.Spec.Hosts,
+			RouteIDs: routeIDs(ri),
+		}
+
+		// Some of this route group's routes may have been dropped
+		// individually (see ctx.result.addDropped in implicitGroupRoutes/
+		// explicitGroupRoutes/appendMirrorRoutes) without the whole
+		// conversion failing; reporting Accepted regardless would leave the
+		// status subresource disagreeing with /kubernetes/diagnostics about
+		// whether this route group came through clean.
+		if len(result.Dropped) > droppedBefore {
+			status.Reason = statusReasonPartial
+			status.Message = fmt.Sprintf("%d route(s) dropped during conversion, see /kubernetes/diagnostics", len(result.Dropped)-droppedBefore)
+		}
+
+		r.reportStatus(rg, status)
+
 		rs = append(rs, ri...)
 	}
 
@@ -558,5 +824,10 @@ func (r *routeGroups) convert(s *clusterState, df defaultFilters) ([]*eskip.Rout
 	})
 
 	rs = append(rs, catchAll...)
-	return rs, nil
-}
\ No newline at end of file
+
+	if r.diagnostics != nil {
+		r.diagnostics.set(result)
+	}
+
+	return rs, result, nil
+}
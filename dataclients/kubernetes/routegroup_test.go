@@ -0,0 +1,146 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+func TestApplyAffinityCookie(t *testing.T) {
+	backend := &skipperBackend{
+		Affinity: &skipperBackendAffinity{Mode: affinityModeCookie, CookieName: "my-cookie"},
+	}
+
+	r := &eskip.Route{}
+	applyAffinity(backend, r)
+
+	if r.LBAlgorithm != "consistentHash" {
+		t.Fatalf("expected consistentHash LB algorithm, got: %s", r.LBAlgorithm)
+	}
+
+	wantKey := "${request.cookie.my-cookie}"
+
+	var haveHashKey, haveResponseCookie bool
+	for _, f := range r.Filters {
+		switch f.Name {
+		case "consistentHashKey":
+			haveHashKey = true
+			if len(f.Args) != 1 || f.Args[0] != wantKey {
+				t.Errorf("consistentHashKey args = %v, want [%q]", f.Args, wantKey)
+			}
+		case "setResponseCookie":
+			haveResponseCookie = true
+			if len(f.Args) != 2 || f.Args[0] != "my-cookie" || f.Args[1] != wantKey {
+				t.Errorf("setResponseCookie args = %v, want [my-cookie %q]", f.Args, wantKey)
+			}
+		}
+	}
+
+	if !haveHashKey || !haveResponseCookie {
+		t.Fatalf("expected consistentHashKey and setResponseCookie filters, got: %v", r.Filters)
+	}
+}
+
+func TestApplyAffinityCookieDefaultName(t *testing.T) {
+	backend := &skipperBackend{Affinity: &skipperBackendAffinity{Mode: affinityModeCookie}}
+
+	r := &eskip.Route{}
+	applyAffinity(backend, r)
+
+	want := "${request.cookie." + defaultAffinityCookieName + "}"
+	for _, f := range r.Filters {
+		if f.Name == "consistentHashKey" && f.Args[0] != want {
+			t.Fatalf("consistentHashKey args = %v, want [%q]", f.Args, want)
+		}
+	}
+}
+
+// TestAppendMirrorRoutesUniqueLoopbackPerRoute checks that mirroring the
+// same backend from two different routes (two different primaries[0].Id
+// values) produces two distinct loopback names, so their Tee-predicated
+// shunt routes don't collide (see appendMirrorRoutes's doc comment).
+func TestAppendMirrorRoutesUniqueLoopbackPerRoute(t *testing.T) {
+	ctx := &routeGroupContext{
+		routeGroup: &routeGroupItem{Metadata: &metadata{Namespace: "default", Name: "rg"}},
+		backendsByName: map[string]*skipperBackend{
+			"shadow": {Name: "shadow", Type: eskip.NetworkBackend, Address: "http://10.0.0.9:8080"},
+		},
+	}
+
+	mirrorRefs := []*backendReference{{BackendName: "shadow", Weight: 100, Mirror: true}}
+
+	primariesA := []*eskip.Route{{Id: "route_a"}}
+	routesA, err := appendMirrorRoutes(ctx, nil, primariesA, mirrorRefs)
+	if err != nil {
+		t.Fatalf("appendMirrorRoutes() error = %v", err)
+	}
+
+	primariesB := []*eskip.Route{{Id: "route_b"}}
+	routesB, err := appendMirrorRoutes(ctx, nil, primariesB, mirrorRefs)
+	if err != nil {
+		t.Fatalf("appendMirrorRoutes() error = %v", err)
+	}
+
+	if len(routesA) != 1 || len(routesB) != 1 {
+		t.Fatalf("expected one shunt route per call, got %d and %d", len(routesA), len(routesB))
+	}
+
+	loopbackA := primariesA[0].Filters[0].Args[0]
+	loopbackB := primariesB[0].Filters[0].Args[0]
+	if loopbackA == loopbackB {
+		t.Fatalf("expected distinct loopback names for distinct routes, both got: %v", loopbackA)
+	}
+
+	if routesA[0].Predicates[0].Args[0] != loopbackA || routesB[0].Predicates[0].Args[0] != loopbackB {
+		t.Fatalf("expected each shunt route's Tee predicate to match its own route's teeLoopback filter")
+	}
+}
+
+// TestRouteGroupsConvertDropsUnresolvableBackend checks that a DefaultBackends
+// entry with no matching Backends definition is dropped individually,
+// reported on the TransformResult, and excluded from calculateTraffic: the
+// surviving backend must get the whole, unsplit traffic share rather than
+// its originally configured weight.
+func TestRouteGroupsConvertDropsUnresolvableBackend(t *testing.T) {
+	r := newRouteGroups(Options{})
+
+	state := &clusterState{
+		routeGroups: []*routeGroupItem{
+			{
+				Metadata: &metadata{Namespace: "default", Name: "rg"},
+				Spec: &routeGroupSpec{
+					Backends: []*skipperBackend{
+						{Name: "good", Type: eskip.LBBackend, Endpoints: []string{"http://10.0.0.1:8080"}},
+					},
+					DefaultBackends: []*backendReference{
+						{BackendName: "good", Weight: 50},
+						{BackendName: "missing", Weight: 50},
+					},
+				},
+			},
+		},
+	}
+
+	routes, result, err := r.convert(state, defaultFilters{})
+	if err != nil {
+		t.Fatalf("convert() error = %v", err)
+	}
+
+	if len(routes) != 1 {
+		t.Fatalf("expected the unresolvable backend's route to be dropped, got %d routes: %v", len(routes), routes)
+	}
+
+	for _, p := range routes[0].Predicates {
+		if p.Name == "Traffic" {
+			t.Fatalf("expected no Traffic predicate once the unresolvable backend is excluded, got: %v", routes[0].Predicates)
+		}
+	}
+
+	if len(result.Dropped) != 1 || result.Dropped[0].Reason != reasonInvalidBackendRef {
+		t.Fatalf("expected one dropped route with reason %s, got: %v", reasonInvalidBackendRef, result.Dropped)
+	}
+
+	if len(result.Accepted) != 1 {
+		t.Fatalf("expected one accepted route, got: %v", result.Accepted)
+	}
+}
@@ -0,0 +1,163 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// statusReason enumerates the well-known reasons reported on the
+// RouteGroup/Gateway API status subresource for the outcome of a
+// conversion, successful or not.
+type statusReason string
+
+const (
+	statusReasonAccepted          statusReason = "Accepted"
+	statusReasonInvalidBackendRef statusReason = "InvalidBackendRef"
+	statusReasonConversionError   statusReason = "ConversionError"
+
+	// statusReasonPartial reports that the object was accepted but one or
+	// more of its routes were dropped individually during conversion; see
+	// TransformResult/the /kubernetes/diagnostics endpoint for which ones.
+	statusReasonPartial statusReason = "Partial"
+)
+
+// resourceStatus is the outcome of converting a single Kubernetes object
+// (a RouteGroup, Gateway or HTTPRoute) into eskip routes.
+type resourceStatus struct {
+	Accepted           bool
+	Reason             statusReason
+	Message            string
+	ObservedGeneration int64
+	Hosts              []string
+	RouteIDs           []string
+}
+
+type statusKey struct {
+	kind      string
+	namespace string
+	name      string
+}
+
+// statusWriter accumulates per-object resourceStatus updates and PATCHes
+// them to the object's /status subresource, coalescing updates that arrive
+// before the next flush so that a busy cluster doesn't turn into a stream
+// of individual API server writes. It is pluggable so that both the
+// RouteGroup dataclient and the Gateway API dataclient can report through
+// the same mechanism.
+type statusWriter struct {
+	client       *clusterClient
+	controllerID string
+	isLeader     func() bool
+	interval     time.Duration
+
+	mu      sync.Mutex
+	pending map[statusKey]pendingStatus
+}
+
+type pendingStatus struct {
+	path   string
+	status *resourceStatus
+}
+
+// newStatusWriter creates a statusWriter that patches status subresources
+// through client, tagging writes with controllerID, and coalescing updates
+// over interval. isLeader is consulted before every flush so that only one
+// Skipper replica writes status at a time; when nil, every replica writes.
+func newStatusWriter(client *clusterClient, controllerID string, interval time.Duration, isLeader func() bool) *statusWriter {
+	if isLeader == nil {
+		isLeader = func() bool { return true }
+	}
+
+	return &statusWriter{
+		client:       client,
+		controllerID: controllerID,
+		isLeader:     isLeader,
+		interval:     interval,
+		pending:      make(map[statusKey]pendingStatus),
+	}
+}
+
+// update queues a status update for the object identified by kind,
+// namespace and name. path is the status subresource path, e.g.
+// "/apis/zalando.org/v1/namespaces/ns/routegroups/name/status". Repeated
+// calls for the same object before the next flush overwrite each other, so
+// only the latest status is ever written.
+func (w *statusWriter) update(kind, namespace, name, path string, status *resourceStatus) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending[statusKey{kind: kind, namespace: namespace, name: name}] = pendingStatus{
+		path:   path,
+		status: status,
+	}
+}
+
+// run flushes the queued status updates every interval, until done is
+// closed. It's meant to be started once, in its own goroutine.
+func (w *statusWriter) run(done <-chan struct{}) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-done:
+			return
+		}
+	}
+}
+
+func (w *statusWriter) flush() {
+	if !w.isLeader() {
+		return
+	}
+
+	w.mu.Lock()
+	batch := w.pending
+	w.pending = make(map[statusKey]pendingStatus)
+	w.mu.Unlock()
+
+	for key, p := range batch {
+		if err := w.patch(p.path, p.status); err != nil {
+			log.Errorf("Failed to write status for %s/%s/%s: %v.", key.kind, key.namespace, key.name, err)
+		}
+	}
+}
+
+func (w *statusWriter) patch(path string, status *resourceStatus) error {
+	body, err := json.Marshal(statusPatch{
+		Status: statusPatchBody{
+			Controller:         w.controllerID,
+			Accepted:           status.Accepted,
+			Reason:             status.Reason,
+			Message:            status.Message,
+			ObservedGeneration: status.ObservedGeneration,
+			Hosts:              status.Hosts,
+			RouteIDs:           status.RouteIDs,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal status patch: %w", err)
+	}
+
+	return w.client.patchStatus(path, body)
+}
+
+type statusPatch struct {
+	Status statusPatchBody `json:"status"`
+}
+
+type statusPatchBody struct {
+	Controller         string       `json:"controller"`
+	Accepted           bool         `json:"accepted"`
+	Reason             statusReason `json:"reason"`
+	Message            string       `json:"message,omitempty"`
+	ObservedGeneration int64        `json:"observedGeneration"`
+	Hosts              []string     `json:"hosts,omitempty"`
+	RouteIDs           []string     `json:"routeIds,omitempty"`
+}
@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestStatusWriterFlushPatchesLatestUpdate checks that update/flush
+// coalesces repeated calls for the same object into a single PATCH carrying
+// only the most recently queued resourceStatus.
+func TestStatusWriterFlushPatchesLatestUpdate(t *testing.T) {
+	var mu sync.Mutex
+	var patches []statusPatch
+	var paths []string
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch {
+			t.Errorf("expected PATCH, got %s", r.Method)
+		}
+
+		var p statusPatch
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			t.Fatalf("failed to decode patch body: %v", err)
+		}
+
+		mu.Lock()
+		patches = append(patches, p)
+		paths = append(paths, r.URL.Path)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cc, err := newClusterClient(Options{KubernetesURL: srv.URL})
+	if err != nil {
+		t.Fatalf("newClusterClient() error = %v", err)
+	}
+
+	sw := newStatusWriter(cc, "test-controller", time.Hour, nil)
+
+	path := "/apis/zalando.org/v1/namespaces/default/routegroups/rg/status"
+	sw.update("routegroup", "default", "rg", path, &resourceStatus{Reason: statusReasonConversionError, Message: "first"})
+	sw.update("routegroup", "default", "rg", path, &resourceStatus{Accepted: true, Reason: statusReasonAccepted, RouteIDs: []string{"r1"}})
+
+	sw.flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(patches) != 1 {
+		t.Fatalf("expected a single coalesced PATCH, got %d: %v", len(patches), patches)
+	}
+
+	if paths[0] != path {
+		t.Errorf("PATCH path = %s, want %s", paths[0], path)
+	}
+
+	got := patches[0].Status
+	if !got.Accepted || got.Reason != statusReasonAccepted || got.Controller != "test-controller" {
+		t.Fatalf("expected the latest queued status to win, got: %#v", got)
+	}
+}
+
+// TestStatusWriterFlushSkipsWhenNotLeader checks that flush does not PATCH
+// anything, and keeps the queued update pending, when isLeader reports false.
+func TestStatusWriterFlushSkipsWhenNotLeader(t *testing.T) {
+	var patched bool
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		patched = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cc, err := newClusterClient(Options{KubernetesURL: srv.URL})
+	if err != nil {
+		t.Fatalf("newClusterClient() error = %v", err)
+	}
+
+	sw := newStatusWriter(cc, "test-controller", time.Hour, func() bool { return false })
+	sw.update("routegroup", "default", "rg", "/status", &resourceStatus{Accepted: true})
+
+	sw.flush()
+
+	if patched {
+		t.Fatal("expected flush to skip patching when not the leader")
+	}
+
+	if len(sw.pending) != 1 {
+		t.Fatalf("expected the update to remain queued, got %d pending", len(sw.pending))
+	}
+}
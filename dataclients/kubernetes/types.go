@@ -0,0 +1,204 @@
+package kubernetes
+
+import (
+	"time"
+
+	"github.com/zalando/skipper/eskip"
+	"github.com/zalando/skipper/loadbalancer"
+)
+
+// Options configures the kubernetes dataclients (RouteGroups and, since the
+// Gateway API dataclient, Gateway API resources).
+type Options struct {
+	// KubernetesURL is the base URL of the Kubernetes API server, e.g.
+	// "https://kubernetes.default.svc.cluster.local".
+	KubernetesURL string
+
+	// KubernetesTokenFile, if set, is read once, when the client is created,
+	// and its token is sent on every request to authenticate against the
+	// API server (the in-cluster service account token).
+	KubernetesTokenFile string
+
+	// PollTimeout is not read by this package: the cluster state is fetched
+	// from the API server anew on every Client.LoadAll call.
+	PollTimeout time.Duration
+
+	KubernetesEastWestDomain string
+	KubernetesEnableEastWest bool
+	ProvideHTTPSRedirect     bool
+	HTTPSRedirectCode        int
+
+	// GatewayAPIEnabled turns on the Gateway API dataclient alongside
+	// RouteGroups.
+	GatewayAPIEnabled bool
+
+	// RouteGroupStatusEnabled turns on writing the status subresource back
+	// to the API server. It covers RouteGroups always, and Gateways/
+	// HTTPRoutes too when GatewayAPIEnabled is also set: both dataclients
+	// report through the same underlying statusWriter.
+	RouteGroupStatusEnabled bool
+
+	// ControllerID identifies this Skipper instance in the status it
+	// writes, e.g. "skipper-ingress-eu-west-1".
+	ControllerID string
+
+	// StatusWriteInterval coalesces status updates that arrive within this
+	// interval into a single PATCH request.
+	StatusWriteInterval time.Duration
+
+	// LeaderElector, if set, is consulted before writing status so that
+	// only one Skipper replica writes at a time. A nil LeaderElector means
+	// every replica writes.
+	LeaderElector LeaderElector
+
+	// DiagnosticsListenAddress, if set, serves the RouteGroup transform
+	// diagnostics (see TransformResult) as JSON on /kubernetes/diagnostics.
+	DiagnosticsListenAddress string
+
+	// XDSListenAddress, if set, serves a JSON snapshot of the xds package's
+	// RouteConfiguration/Cluster rendering of the current routes on
+	// /xds/routes. It is not an Envoy ADS gRPC endpoint: there is no
+	// go-control-plane server behind it yet, just the same intermediate
+	// representation Envoy's RDS/CDS responses would be built from.
+	XDSListenAddress string
+}
+
+// LeaderElector reports whether the current process is allowed to perform
+// singleton actions, such as writing status. Implementations typically wrap
+// a Kubernetes Lease or a similar coordination primitive.
+type LeaderElector interface {
+	IsLeader() bool
+}
+
+type metadata struct {
+	Namespace   string            `json:"namespace"`
+	Name        string            `json:"name"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	Generation  int64             `json:"generation"`
+}
+
+type routeGroupItem struct {
+	Metadata *metadata       `json:"metadata"`
+	Spec     *routeGroupSpec `json:"spec"`
+}
+
+type routeGroupSpec struct {
+	Hosts           []string            `json:"hosts"`
+	Backends        []*skipperBackend   `json:"backends"`
+	DefaultBackends []*backendReference `json:"defaultBackends"`
+	Routes          []*routeSpec        `json:"routes"`
+}
+
+type routeSpec struct {
+	Path        string   `json:"path"`
+	PathSubtree string   `json:"pathSubtree"`
+	PathRegexp  string   `json:"pathRegexp"`
+	Methods     []string `json:"methods"`
+	Predicates  []string `json:"predicates"`
+	Filters     []string `json:"filters"`
+
+	Backends []*backendReference `json:"backends"`
+}
+
+// backendReference points at one of the RouteGroup's named backends, with a
+// traffic weight. Mirror marks it as a shadow-traffic target instead of a
+// regular load-balanced backend: see appendMirrorRoutes.
+type backendReference struct {
+	BackendName string `json:"backendName"`
+	Weight      int    `json:"weight"`
+	Mirror      bool   `json:"mirror"`
+}
+
+// skipperBackend is a named backend definition in a RouteGroup, referenced
+// by backendReference.BackendName.
+type skipperBackend struct {
+	Name        string                 `json:"name"`
+	Type        eskip.BackendType      `json:"type"`
+	Address     string                 `json:"address"`
+	ServiceName string                 `json:"serviceName"`
+	ServicePort int                    `json:"servicePort"`
+	Algorithm   loadbalancer.Algorithm `json:"algorithm"`
+	Endpoints   []string               `json:"endpoints"`
+
+	// Affinity, when set, makes this backend use consistent-hash load
+	// balancing with a sticky session key. See applyAffinity.
+	Affinity *skipperBackendAffinity `json:"affinity"`
+}
+
+// skipperBackendAffinity configures session affinity for a backend. Mode
+// selects how the consistent-hash key is derived: "cookie" (sticky via
+// CookieName), "header" (via HeaderName), "sourceIP" (via the client's
+// remote address), or "consistentHash" (via the raw Key expression, for
+// cases none of the above cover, e.g. a query parameter).
+type skipperBackendAffinity struct {
+	Mode       string `json:"mode"`
+	CookieName string `json:"cookieName"`
+	HeaderName string `json:"headerName"`
+	Key        string `json:"key"`
+}
+
+// serviceBackend is the skipperBackend.Type value for backends resolved
+// through a Kubernetes Service, as opposed to a static address or endpoint
+// list.
+const serviceBackend eskip.BackendType = eskip.LBBackend + 1
+
+type service struct {
+	Meta *metadata    `json:"metadata"`
+	Spec *serviceSpec `json:"spec"`
+}
+
+type serviceSpec struct {
+	Type      string         `json:"type"`
+	ClusterIP string         `json:"clusterIP"`
+	Ports     []*servicePort `json:"ports"`
+}
+
+type servicePort struct {
+	Name       string `json:"name"`
+	Port       int    `json:"port"`
+	TargetPort int    `json:"targetPort"`
+}
+
+// getTargetPortByValue resolves the numeric container target port for the
+// given service port value, falling back to the service port itself when no
+// targetPort is set. Named target ports are not supported.
+func (s *service) getTargetPortByValue(port int) (int, bool) {
+	for _, p := range s.Spec.Ports {
+		if p != nil && p.Port == port {
+			if p.TargetPort != 0 {
+				return p.TargetPort, true
+			}
+
+			return p.Port, true
+		}
+	}
+
+	return 0, false
+}
+
+// defaultFilters looks up the cluster-wide default filters configured for a
+// given namespace/service, as set via Skipper's default filters CRD or
+// flag.
+type defaultFilters struct {
+	bySvc map[string][]*eskip.Filter
+}
+
+// getNamed returns a copy of the default filters configured for
+// namespace/serviceName, or nil if none are configured.
+func (d defaultFilters) getNamed(namespace, serviceName string) ([]*eskip.Filter, error) {
+	if d.bySvc == nil {
+		return nil, nil
+	}
+
+	f := d.bySvc[namespace+"/"+serviceName]
+	if f == nil {
+		return nil, nil
+	}
+
+	cp := make([]*eskip.Filter, len(f))
+	copy(cp, f)
+	return cp, nil
+}
+
+const defaultLoadBalancerAlgorithm = "roundRobin"
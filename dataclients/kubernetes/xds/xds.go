@@ -0,0 +1,300 @@
+// Package xds renders the eskip routes produced by the kubernetes
+// dataclients (RouteGroups and, when enabled, Gateway API) as Envoy xDS
+// resources, so that Skipper can additionally act as an Envoy control
+// plane without changing the existing eskip pipeline. It consumes the
+// same intermediate representation, []*eskip.Route, that the rest of
+// Skipper already uses, rather than reaching back into the Kubernetes
+// cluster state directly.
+package xds
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+// RouteConfiguration is a minimal stand-in for
+// envoy.config.route.v3.RouteConfiguration. Field names follow the Envoy
+// proto closely so that a later switch to the real go-control-plane types
+// is a rename, not a redesign.
+type RouteConfiguration struct {
+	Name         string
+	VirtualHosts []*VirtualHost
+}
+
+type VirtualHost struct {
+	Name    string
+	Domains []string
+	Routes  []*Route
+}
+
+type Route struct {
+	Match *RouteMatch
+	// Exactly one of Cluster or WeightedClusters is set.
+	Cluster          string
+	WeightedClusters []*WeightedCluster
+}
+
+type RouteMatch struct {
+	Path      string
+	Prefix    string
+	SafeRegex string
+	Headers   []*HeaderMatch
+}
+
+type HeaderMatch struct {
+	Name       string
+	ExactMatch string
+	HeaderName string // ":method" for Method predicates
+}
+
+type WeightedCluster struct {
+	Name   string
+	Weight int
+}
+
+// Cluster is a minimal stand-in for envoy.config.cluster.v3.Cluster.
+type Cluster struct {
+	Name      string
+	Type      string // STRICT_DNS or EDS
+	Endpoints []string
+}
+
+// FromRoutes groups eskip routes by their Host predicate into Envoy
+// VirtualHosts, and renders each route's predicates/backend as an Envoy
+// RouteMatch/cluster reference. Routes without a Host predicate are placed
+// in a single catch-all virtual host matching "*". Routes that only differ
+// by their Traffic predicate and otherwise match the same host/path/method
+// (as produced by the kubernetes dataclients' traffic-splitting, see
+// calculateTraffic) are folded into a single Envoy Route with one
+// WeightedCluster per split, rather than left as independent routes that
+// Envoy would evaluate in list order instead of by weight.
+func FromRoutes(name string, routes []*eskip.Route) (*RouteConfiguration, []*Cluster, error) {
+	byHost := make(map[string][]*eskip.Route)
+	for _, r := range routes {
+		hosts := hostDomains(r)
+		if len(hosts) == 0 {
+			hosts = []string{"*"}
+		}
+
+		for _, h := range hosts {
+			byHost[h] = append(byHost[h], r)
+		}
+	}
+
+	domains := make([]string, 0, len(byHost))
+	for h := range byHost {
+		domains = append(domains, h)
+	}
+	sort.Strings(domains)
+
+	clustersByName := make(map[string]*Cluster)
+	rc := &RouteConfiguration{Name: name}
+	for _, domain := range domains {
+		vh := &VirtualHost{Name: virtualHostName(domain), Domains: []string{domain}}
+
+		rendered := make([]*renderedRoute, 0, len(byHost[domain]))
+		for _, r := range byHost[domain] {
+			rr, err := renderRoute(r)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to render route %s: %w", r.Id, err)
+			}
+
+			rendered = append(rendered, rr)
+			if rr.cluster != nil {
+				clustersByName[rr.cluster.Name] = rr.cluster
+			}
+		}
+
+		vh.Routes = groupSplitRoutes(rendered)
+		rc.VirtualHosts = append(rc.VirtualHosts, vh)
+	}
+
+	clusters := make([]*Cluster, 0, len(clustersByName))
+	for _, c := range clustersByName {
+		clusters = append(clusters, c)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+
+	return rc, clusters, nil
+}
+
+// renderedRoute is the per-eskip.Route intermediate result of renderRoute,
+// before sibling traffic-split routes are folded together by
+// groupSplitRoutes.
+type renderedRoute struct {
+	match   *RouteMatch
+	weight  float64 // this route's Traffic predicate value, or 1 if it has none
+	cluster *Cluster
+}
+
+// groupSplitRoutes folds all renderedRoutes that share the same RouteMatch,
+// whether adjacent or not, into a single Envoy Route, placed where the
+// first of them was. A lone route keeps a plain Cluster
+// reference; a group of two or more becomes one Route with a
+// WeightedCluster per member, reconstructing each member's relative weight
+// from its sequential remainder-of-traffic fraction (see calculateTraffic
+// in routegroup.go) so that the weights sum to the same total regardless of
+// how many splits there are.
+func groupSplitRoutes(rendered []*renderedRoute) []*Route {
+	var keys []string
+	groups := make(map[string][]*renderedRoute)
+	for _, rr := range rendered {
+		k := matchKey(rr.match)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+
+		groups[k] = append(groups[k], rr)
+	}
+
+	routes := make([]*Route, 0, len(keys))
+	for _, k := range keys {
+		group := groups[k]
+		er := &Route{Match: group[0].match}
+
+		if len(group) == 1 {
+			if group[0].cluster != nil {
+				er.Cluster = group[0].cluster.Name
+			}
+
+			routes = append(routes, er)
+			continue
+		}
+
+		for name, weight := range splitWeights(group) {
+			er.WeightedClusters = append(er.WeightedClusters, &WeightedCluster{Name: name, Weight: weight})
+		}
+
+		sort.Slice(er.WeightedClusters, func(i, j int) bool { return er.WeightedClusters[i].Name < er.WeightedClusters[j].Name })
+		routes = append(routes, er)
+	}
+
+	return routes
+}
+
+// splitWeights reconstructs each group member's relative share of total
+// traffic from its sequential remainder fraction, and scales the result to
+// integer weights summing to 100.
+func splitWeights(group []*renderedRoute) map[string]int {
+	shares := make([]float64, len(group))
+	remaining := 1.0
+	for i, rr := range group {
+		share := rr.weight * remaining
+		shares[i] = share
+		remaining -= share
+	}
+
+	weights := make(map[string]int, len(group))
+	var assigned int
+	for i, rr := range group {
+		if rr.cluster == nil {
+			continue
+		}
+
+		w := int(shares[i] * 100)
+		weights[rr.cluster.Name] += w
+		assigned += w
+	}
+
+	// Rounding can leave the total a little under 100; hand the remainder
+	// to the last member so the weights always sum to exactly 100.
+	if last := group[len(group)-1]; last.cluster != nil && assigned != 100 {
+		weights[last.cluster.Name] += 100 - assigned
+	}
+
+	return weights
+}
+
+// matchKey canonicalizes a RouteMatch into a string that is equal for two
+// RouteMatches that would match identically, for grouping sibling
+// traffic-split routes in groupSplitRoutes.
+func matchKey(m *RouteMatch) string {
+	headers := make([]string, len(m.Headers))
+	for i, h := range m.Headers {
+		headers[i] = h.HeaderName + "=" + h.ExactMatch
+	}
+	sort.Strings(headers)
+
+	return strings.Join([]string{m.Path, m.Prefix, m.SafeRegex, strings.Join(headers, ",")}, "\x00")
+}
+
+func hostDomains(r *eskip.Route) []string {
+	var domains []string
+	for _, p := range r.Predicates {
+		if p.Name == "Host" && len(p.Args) == 1 {
+			if s, ok := p.Args[0].(string); ok {
+				domains = append(domains, s)
+			}
+		}
+	}
+
+	return domains
+}
+
+// renderRoute translates a single eskip.Route's predicates and backend into
+// a RouteMatch and Cluster. Its Traffic predicate, if any, is captured as
+// the weight rather than rendered into the match, so that groupSplitRoutes
+// can fold traffic-split siblings back into one Envoy Route afterwards.
+func renderRoute(r *eskip.Route) (*renderedRoute, error) {
+	match := &RouteMatch{}
+	weight := 1.0
+
+	for _, p := range r.Predicates {
+		switch p.Name {
+		case "Path":
+			match.Path = argString(p, 0)
+		case "PathSubtree":
+			match.Prefix = argString(p, 0)
+		case "PathRegexp":
+			match.SafeRegex = argString(p, 0)
+		case "Method":
+			match.Headers = append(match.Headers, &HeaderMatch{HeaderName: ":method", ExactMatch: argString(p, 0)})
+		case "Header":
+			match.Headers = append(match.Headers, &HeaderMatch{HeaderName: argString(p, 0), ExactMatch: argString(p, 1)})
+		case "Traffic":
+			if f, ok := argFloat(p, 0); ok {
+				weight = f
+			}
+		}
+	}
+
+	var cluster *Cluster
+	switch {
+	case len(r.LBEndpoints) > 0:
+		cluster = &Cluster{Name: clusterNameForRoute(r), Type: "EDS", Endpoints: r.LBEndpoints}
+	case r.Backend != "":
+		cluster = &Cluster{Name: clusterNameForRoute(r), Type: "STRICT_DNS", Endpoints: []string{r.Backend}}
+	}
+
+	return &renderedRoute{match: match, weight: weight, cluster: cluster}, nil
+}
+
+func clusterNameForRoute(r *eskip.Route) string {
+	return "cluster_" + r.Id
+}
+
+func virtualHostName(domain string) string {
+	return "vhost_" + domain
+}
+
+func argString(p *eskip.Predicate, i int) string {
+	if i >= len(p.Args) {
+		return ""
+	}
+
+	s, _ := p.Args[i].(string)
+	return s
+}
+
+func argFloat(p *eskip.Predicate, i int) (float64, bool) {
+	if i >= len(p.Args) {
+		return 0, false
+	}
+
+	f, ok := p.Args[i].(float64)
+	return f, ok
+}
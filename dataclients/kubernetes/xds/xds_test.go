@@ -0,0 +1,108 @@
+package xds
+
+import (
+	"testing"
+
+	"github.com/zalando/skipper/eskip"
+)
+
+func trafficPredicate(v float64) []*eskip.Predicate {
+	return []*eskip.Predicate{{Name: "Traffic", Args: []interface{}{v}}}
+}
+
+// TestFromRoutesWeightedSplit checks that three traffic-split siblings (the
+// sequential remainder-of-traffic fractions calculateTraffic produces for a
+// 50/30/20 weight split) are folded into one Envoy Route with WeightedClusters
+// that reconstruct the original 50/30/20 shares, summing to 100.
+func TestFromRoutesWeightedSplit(t *testing.T) {
+	routes := []*eskip.Route{
+		{
+			Id:          "primary",
+			Predicates:  append([]*eskip.Predicate{{Name: "Path", Args: []interface{}{"/api"}}}, trafficPredicate(0.5)...),
+			LBEndpoints: []string{"http://10.0.0.1:8080"},
+		},
+		{
+			Id:          "secondary",
+			Predicates:  append([]*eskip.Predicate{{Name: "Path", Args: []interface{}{"/api"}}}, trafficPredicate(0.6)...),
+			LBEndpoints: []string{"http://10.0.0.2:8080"},
+		},
+		{
+			Id:          "tertiary",
+			Predicates:  []*eskip.Predicate{{Name: "Path", Args: []interface{}{"/api"}}},
+			LBEndpoints: []string{"http://10.0.0.3:8080"},
+		},
+	}
+
+	rc, clusters, err := FromRoutes("test", routes)
+	if err != nil {
+		t.Fatalf("FromRoutes() error = %v", err)
+	}
+
+	if len(clusters) != 3 {
+		t.Fatalf("expected 3 clusters, got %d: %v", len(clusters), clusters)
+	}
+
+	if len(rc.VirtualHosts) != 1 {
+		t.Fatalf("expected a single catch-all virtual host, got %d: %v", len(rc.VirtualHosts), rc.VirtualHosts)
+	}
+
+	vh := rc.VirtualHosts[0]
+	if len(vh.Routes) != 1 {
+		t.Fatalf("expected the 3 siblings to fold into 1 route, got %d: %v", len(vh.Routes), vh.Routes)
+	}
+
+	route := vh.Routes[0]
+	if len(route.WeightedClusters) != 3 {
+		t.Fatalf("expected 3 weighted clusters, got %d: %v", len(route.WeightedClusters), route.WeightedClusters)
+	}
+
+	weights := make(map[string]int, 3)
+	var total int
+	for _, wc := range route.WeightedClusters {
+		weights[wc.Name] = wc.Weight
+		total += wc.Weight
+	}
+
+	if total != 100 {
+		t.Fatalf("expected weights to sum to 100, got %d: %v", total, weights)
+	}
+
+	if w := weights["cluster_primary"]; w != 50 {
+		t.Errorf("cluster_primary weight = %d, want 50", w)
+	}
+
+	if w := weights["cluster_secondary"]; w != 30 {
+		t.Errorf("cluster_secondary weight = %d, want 30", w)
+	}
+
+	if w := weights["cluster_tertiary"]; w != 20 {
+		t.Errorf("cluster_tertiary weight = %d, want 20", w)
+	}
+}
+
+// TestFromRoutesSingleRouteNoSplit checks that a route with no traffic-split
+// sibling keeps a plain Cluster reference instead of a single-entry
+// WeightedClusters list.
+func TestFromRoutesSingleRouteNoSplit(t *testing.T) {
+	routes := []*eskip.Route{
+		{
+			Id:         "solo",
+			Predicates: []*eskip.Predicate{{Name: "Host", Args: []interface{}{"example.org"}}},
+			Backend:    "http://10.0.0.1:8080",
+		},
+	}
+
+	rc, _, err := FromRoutes("test", routes)
+	if err != nil {
+		t.Fatalf("FromRoutes() error = %v", err)
+	}
+
+	if len(rc.VirtualHosts) != 1 || len(rc.VirtualHosts[0].Routes) != 1 {
+		t.Fatalf("expected exactly one route, got: %#v", rc.VirtualHosts)
+	}
+
+	route := rc.VirtualHosts[0].Routes[0]
+	if route.Cluster != "cluster_solo" || len(route.WeightedClusters) != 0 {
+		t.Fatalf("expected a plain Cluster reference, got: %#v", route)
+	}
+}